@@ -1,5 +1,30 @@
 // Package gu_flag implements an extension to the standard flag package.
 // It has the following features:
-//   - keyword-selected subsets of command-line switches
-//   - simple handling of multiple-values args
+//   - keyword-selected subsets of command-line switches, selected also
+//     through aliases or unambiguous prefixes
+//   - simple handling of multiple-values args, also typed (ints,
+//     floats, durations, key=value maps)
+//   - help rendered for any keyword path
+//   - bash, zsh and fish completion scripts
+//   - flag values taken from environment variables
+//   - defaults read from JSON configuration files
+//   - optional GNU style parsing, with bundled short flags
+//   - required, mutually exclusive and dependent flags
+//   - persistent flags, accepted also below their keyword
+//   - named and typed positional args
+//   - typed parse errors, suggesting the intended keyword or flag
+//   - grammars declared through tagged struct fields
+//   - man pages and Markdown reference generated from the keyword tree
+//   - PreRun and PostRun hooks along the keyword path, sharing a context
+//   - independent, resettable and clonable grammars, beside MainSet
+//   - set values edited with +/- operators and matched by wildcards
+//   - hidden and deprecated flags and keywords, with removal versions
+//   - an interactive shell running commands of the keyword tree
+//   - optional @file response files, with errors pointing to file and line
+//   - export of the effective values and their origins, to be replayed
+//   - single valued enum flags
+//   - byte size and rate flags, the latter driving gu_time throttlers
+//   - -log-level, -v and -debug-log flags configuring gu_log
+//   - Execute, turning the outcome of a command into an exit code
+//   - git style plugins, external commands found in PATH as keywords
 package gu_flag
@@ -0,0 +1,175 @@
+// A grammar can be parsed again and again: Reset brings back the
+// defaults between parses, and Clone gives a copy of a tree storing its values apart,
+// so that different copies can be parsed at the same time
+package gu_flag
+
+import (
+	"flag"
+	"reflect"
+
+	"github.com/maxcalandrelli/goutil/encoding/json"
+)
+
+// Reset restores the defaults of the flags and positional args of this
+// FlagSet and of all the subgrammars below it, forgetting where values
+// came from. Only the values created by this package are restored: the
+// repeatable and typed ones, and the basic ones defined on the Flags
+// made by NewFlagSet, leaving alone the ones of flag.CommandLine, in
+// MainSet, or of any other flag.FlagSet put in Flags. Parse calls Reset
+// before parsing only when ResetOnParse is set on the FlagSet parsing
+func (f *FlagSet) Reset() {
+	f.Walk(func(s *FlagSet) {
+		s.clearParseState()
+		s.Flags.VisitAll(func(fl *flag.Flag) {
+			if _, isAlias := s.aliases[fl.Name]; isAlias {
+				return
+			}
+			if ra, ok := fl.Value.(RepeatableArg); ok {
+				ra.restoreDefault()
+			} else if dv, ok := fl.Value.(defaultedValue); ok {
+				dv.restoreDefault()
+			} else if s.Flags == s.ownFlags && isBasicValue(fl.Value) {
+				fl.Value.Set(fl.DefValue)
+			}
+		})
+		for _, pa := range s.positionals {
+			pa.clear()
+		}
+	})
+}
+
+// clearParseState forgets the outcome of the last parse, leaving the
+// values alone
+func (f *FlagSet) clearParseState() {
+	f.origins = nil
+	f.args = nil
+	f.ctx = nil
+	f.selected = nil
+}
+
+// Value returns the current value of the named flag, as returned by
+// flag.Getter, or nil if the flag is not defined
+func (f *FlagSet) Value(name string) interface{} {
+	fl, _ := f.lookup(name)
+	if fl == nil {
+		return nil
+	}
+	if g, ok := fl.Value.(flag.Getter); ok {
+		return g.Get()
+	}
+	return fl.Value.String()
+}
+
+// Clone returns a copy of the tree below this FlagSet, as a new root,
+// with all the values set to their defaults; values are read back with
+// Value and PositionalArg.Values. Not everything can be duplicated:
+//   - flags of types other than the basic ones and the ones defined by
+//     this package, like the ones of flag.Func, share their Value with
+//     the original tree
+//   - HookFunc, PreRun, PostRun, the Usage function of Flags and the
+//     validators of positional args are copied as they are, so closures
+//     still refer to the variables of the original tree
+//   - plugins already found are kept, with their executables
+func (f *FlagSet) Clone() *FlagSet {
+	return f.clone(nil)
+}
+
+func (f *FlagSet) clone(parent *FlagSet) *FlagSet {
+	c := newFlagSet(f.subCommand, f.usage, append([]string{}, f.keywordAliases...))
+	c.Flags = flag.NewFlagSet(f.Flags.Name(), f.Flags.ErrorHandling())
+	c.Flags.Usage = f.Flags.Usage
+	c.ownFlags = c.Flags
+	c.parent = parent
+	c.NonKeywordArgs = f.NonKeywordArgs
+	c.GNUStyle = f.GNUStyle
+	c.PrefixMatching = f.PrefixMatching
+	c.ResponseFiles = f.ResponseFiles
+	c.Hidden = f.Hidden
+	c.ResetOnParse = f.ResetOnParse
+	c.deprecation = f.deprecation
+	c.version = f.version
+	c.logger = f.logger
+	c.HookFunc = f.HookFunc
+	c.pluginDirs = f.pluginDirs
+	c.pluginsEnabled = f.pluginsEnabled
+	c.pluginsLoaded = f.pluginsLoaded
+	c.plugin = f.plugin
+	c.pluginOutput = f.pluginOutput
+	c.PreRun = f.PreRun
+	c.PostRun = f.PostRun
+	c.output = f.output
+	c.envBound = f.envBound
+	c.envPrefix = f.envPrefix
+	c.configs = append([]gu_json.JSONData{}, f.configs...)
+	c.constraints = append([]constraint{}, f.constraints...)
+	for n, _ := range f.persistent {
+		c.Persist(n)
+	}
+	f.Flags.VisitAll(func(fl *flag.Flag) {
+		if _, isAlias := f.aliases[fl.Name]; isAlias {
+			return
+		}
+		if ra, ok := fl.Value.(RepeatableArg); ok {
+			ra.clone(c, fl.Name)
+		} else if dv, ok := fl.Value.(defaultedValue); ok {
+			dv.redefine(c, fl.Name)
+		} else {
+			c.Flags.Var(cloneValue(fl), fl.Name, fl.Usage)
+		}
+	})
+	for a, n := range f.aliases {
+		c.Alias(a, n)
+	}
+	for n, _ := range f.hidden {
+		c.HideFlags(n)
+	}
+	for n, d := range f.deprecated {
+		if c.deprecated == nil {
+			c.deprecated = map[string]*Deprecation{}
+		}
+		c.deprecated[n] = d
+	}
+	for _, pa := range f.positionals {
+		cpa := pa.factory(c)
+		cpa.min, cpa.max = pa.min, pa.max
+		cpa.validators = append([]func(string) error{}, pa.validators...)
+	}
+	for k, s := range f.subSets {
+		c.subSets[k] = s.clone(c)
+	}
+	return c
+}
+
+// defaultedValue is implemented by the single valued flags of this
+// package that remember their default
+type defaultedValue interface {
+	restoreDefault()
+	redefine(*FlagSet, string)
+}
+
+// isBasicValue tells the values of the basic types of the flag package,
+// that can be safely set to their default; other values, like the ones
+// of flag.Func, are left alone
+func isBasicValue(v flag.Value) bool {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return false
+	}
+	switch rv.Elem().Kind() {
+	case reflect.Bool, reflect.String, reflect.Float64,
+		reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// cloneValue gives fresh storage to the values of the basic types, set
+// to the default of fl
+func cloneValue(fl *flag.Flag) flag.Value {
+	if !isBasicValue(fl.Value) {
+		return fl.Value
+	}
+	r := reflect.New(reflect.ValueOf(fl.Value).Elem().Type()).Interface().(flag.Value)
+	r.Set(fl.DefValue)
+	return r
+}
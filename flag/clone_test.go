@@ -0,0 +1,128 @@
+package gu_flag
+
+import (
+	"flag"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func cloneTree() *FlagSet {
+	root := NewRoot("prog", "")
+	root.Flags.Bool("verbose", false, "")
+	hat := root.NewFlagSet("hat", "")
+	hat.Flags.String("style", "plain", "")
+	hat.List("shade", []string{"dark"}, "")
+	hat.Enum("size", "m", []string{"s", "m", "l"}, "", false)
+	var name string
+	hat.StringArg(&name, "name", "").Optional()
+	return root
+}
+
+func TestReset(t *testing.T) {
+	root := cloneTree()
+	root.ResetOnParse = true
+	hat := root.subSets["hat"]
+	if err := root.Parse(strings.Fields("-verbose hat -style=x -shade=a -size=l joe")); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Parse([]string{"hat"}); err != nil {
+		t.Fatal(err)
+	}
+	if root.Value("verbose") != false || hat.Value("style") != "plain" || hat.Value("size") != "m" {
+		t.Errorf("values not reset: %v %v %v", root.Value("verbose"), hat.Value("style"), hat.Value("size"))
+	}
+	if shade := hat.Value("shade").([]string); strings.Join(shade, ",") != "dark" {
+		t.Errorf("shade not reset: %v", shade)
+	}
+	if len(hat.Positionals()[0].Values()) != 0 {
+		t.Errorf("positional not reset")
+	}
+	if o := hat.Origin("style"); o != OriginDefault {
+		t.Errorf("origin not reset: %v", o)
+	}
+}
+
+func TestResetForeignFlags(t *testing.T) {
+	root := cloneTree()
+	foreign := flag.NewFlagSet("prog", flag.ContinueOnError)
+	level := foreign.Int("level", 1, "")
+	shade := (&FlagSet{Flags: foreign}).List("shade", []string{"dark"}, "")
+	root.Flags = foreign
+	if err := root.Parse(strings.Fields("-level=3 -shade=a hat")); err != nil {
+		t.Fatal(err)
+	}
+	root.Reset()
+	if *level != 3 {
+		t.Errorf("flag of a foreign flag.FlagSet reset to %d", *level)
+	}
+	if strings.Join(shade.GetValues(), ",") != "dark" {
+		t.Errorf("repeatable flag not reset: %v", shade.GetValues())
+	}
+}
+
+func TestParseKeepsValues(t *testing.T) {
+	root := cloneTree()
+	hat := root.subSets["hat"]
+	root.Parse(strings.Fields("hat -style=x"))
+	if err := root.Parse(strings.Fields("-verbose hat")); err != nil {
+		t.Fatal(err)
+	}
+	if hat.Value("style") != "x" || root.Value("verbose") != true {
+		t.Errorf("values reset: %v %v", hat.Value("style"), root.Value("verbose"))
+	}
+	if o := hat.Origin("style"); o != OriginDefault {
+		t.Errorf("origin kept: %v", o)
+	}
+	hat.Flags.Set("style", "y")
+	if err := root.Parse([]string{"hat"}); err != nil || hat.Value("style") != "y" {
+		t.Errorf("value set before parsing lost: %v %v", hat.Value("style"), err)
+	}
+}
+
+func TestResetNilDefault(t *testing.T) {
+	root := NewRoot("prog", "")
+	var tags []string
+	root.SetVar(&tags, "tag", nil, "", false)
+	if err := root.Parse([]string{"-tag=a"}); err != nil {
+		t.Fatal(err)
+	}
+	root.Reset()
+	if tags != nil {
+		t.Errorf("got %#v", tags)
+	}
+}
+
+func TestClone(t *testing.T) {
+	root := cloneTree()
+	root.ResponseFiles = true
+	if err := root.Parse(strings.Fields("hat -style=x")); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	clones := make([]*FlagSet, 4)
+	for i := range clones {
+		clones[i] = root.Clone()
+		wg.Add(1)
+		go func(c *FlagSet, style string) {
+			defer wg.Done()
+			if err := c.Parse([]string{"hat", "-style=" + style, "-shade=" + style, style}); err != nil {
+				t.Error(err)
+			}
+		}(clones[i], string(rune('a'+i)))
+	}
+	wg.Wait()
+	for i, c := range clones {
+		hat := c.subSets["hat"]
+		want := string(rune('a' + i))
+		if hat.Value("style") != want || strings.Join(hat.Value("shade").([]string), ",") != want || hat.Positionals()[0].Values()[0] != want {
+			t.Errorf("clone %d: got %v %v %v", i, hat.Value("style"), hat.Value("shade"), hat.Positionals()[0].Values())
+		}
+		if !c.ResponseFiles {
+			t.Errorf("clone %d: settings not copied", i)
+		}
+	}
+	if v := root.subSets["hat"].Value("style"); v != "x" {
+		t.Errorf("original changed: %v", v)
+	}
+}
@@ -0,0 +1,254 @@
+// Completion scripts for bash, zsh and fish are generated walking the
+// keyword tree: keywords and flags are completed at the level reached
+// by the words already typed, and the values of constrained sets after
+// the = sign of their flag
+package gu_flag
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+type completionFlag struct {
+	name       string
+	usage      string
+	takesValue bool
+	values     []string
+}
+
+type completionNode struct {
+	key      string
+	set      *FlagSet
+	keywords []string
+	flags    []completionFlag
+}
+
+var nonIdentifier = regexp.MustCompile("[^A-Za-z0-9_]")
+
+func completionKey(f *FlagSet) string {
+	return "/" + strings.Join(f.Path(), "/")
+}
+
+func allowedValues(fl *flag.Flag) []string {
+	if av, ok := fl.Value.(interface{ AllowedValues() []string }); ok {
+		return av.AllowedValues()
+	}
+	return nil
+}
+
+func completionNodes(root *FlagSet) []completionNode {
+	nodes := []completionNode{}
+	root.walkVisible(func(s *FlagSet) {
+		n := completionNode{key: completionKey(s), set: s, keywords: s.visibleKeywords()}
+		s.visitAll(func(fl *flag.Flag) {
+			if s.isHiddenFlag(fl.Name) {
+				return
+			}
+			n.flags = append(n.flags, completionFlag{
+				name:       fl.Name,
+				usage:      fl.Usage,
+				takesValue: !isBoolFlag(fl),
+				values:     allowedValues(fl),
+			})
+		})
+		nodes = append(nodes, n)
+	})
+	return nodes
+}
+
+// words returns what can be typed at this level: keywords, boolean
+// flags and, followed by =, flags requiring a value
+func (n completionNode) words() (plain, withValue []string) {
+	plain = append(plain, n.keywords...)
+	for _, fl := range n.flags {
+		if fl.takesValue {
+			withValue = append(withValue, "-"+fl.name+"=")
+		} else {
+			plain = append(plain, "-"+fl.name)
+		}
+	}
+	return
+}
+
+func completionFunction(prog string) string {
+	return "_" + nonIdentifier.ReplaceAllString(prog, "_") + "_gu_complete"
+}
+
+// writeTransitions emits the shell case arms moving from a level to the
+// one selected by a keyword; the same syntax is valid in bash and zsh
+func writeTransitions(w io.Writer, nodes []completionNode, indent string) {
+	for _, n := range nodes {
+		for _, k := range n.keywords {
+			sub := n.set.subSets[k]
+			for _, a := range append([]string{k}, sub.keywordAliases...) {
+				fmt.Fprintf(w, "%s\"%s %s\") path=\"%s\" ;;\n", indent, n.key, a, completionKey(sub))
+			}
+		}
+	}
+}
+
+func writeValueCases(w io.Writer, nodes []completionNode, indent string) {
+	for _, n := range nodes {
+		for _, fl := range n.flags {
+			if len(fl.values) > 0 {
+				fmt.Fprintf(w, "%s\"%s %s\") values=\"%s\" ;;\n", indent, n.key, fl.name, strings.Join(fl.values, " "))
+			}
+		}
+	}
+}
+
+func (f *FlagSet) GenBashCompletion(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	root := f.Root()
+	prog := root.ProgramName()
+	fn := completionFunction(prog)
+	nodes := completionNodes(root)
+	fmt.Fprintf(bw, "# bash completion for %s\n\n%s() {\n", prog, fn)
+	fmt.Fprintf(bw, `    local cur prev path w i flag values head
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    path="/"
+    for ((i=1; i<COMP_CWORD; i++)); do
+        w="${COMP_WORDS[i]}"
+        case "$path $w" in
+`)
+	writeTransitions(bw, nodes, "        ")
+	fmt.Fprintf(bw, `        esac
+    done
+    flag=""
+    if [[ "$cur" == "=" ]]; then
+        flag="$prev"
+        cur=""
+    elif [[ "$prev" == "=" && COMP_CWORD -gt 1 ]]; then
+        flag="${COMP_WORDS[COMP_CWORD-2]}"
+    elif [[ "$cur" == -*=* ]]; then
+        flag="${cur%%%%=*}"
+        cur="${cur#*=}"
+    fi
+    if [[ -n "$flag" ]]; then
+        flag="${flag#-}"
+        flag="${flag#-}"
+        values=""
+        case "$path $flag" in
+`)
+	writeValueCases(bw, nodes, "        ")
+	fmt.Fprintf(bw, `        esac
+        head=""
+        if [[ "$cur" == *,* ]]; then
+            head="${cur%%,*},"
+        fi
+        COMPREPLY=( $(compgen -P "$head" -W "$values" -- "${cur##*,}") )
+        return 0
+    fi
+    case "$path" in
+`)
+	for _, n := range nodes {
+		plain, withValue := n.words()
+		fmt.Fprintf(bw, "        \"%s\") values=\"%s\" ;;\n", n.key, strings.Join(append(plain, withValue...), " "))
+	}
+	fmt.Fprintf(bw, `    esac
+    COMPREPLY=( $(compgen -W "$values" -- "$cur") )
+    if [[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == *= ]]; then
+        compopt -o nospace 2>/dev/null
+    fi
+    return 0
+}
+
+complete -F %s %s
+`, fn, prog)
+	return bw.Flush()
+}
+
+func (f *FlagSet) GenZshCompletion(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	root := f.Root()
+	prog := root.ProgramName()
+	fn := completionFunction(prog)
+	nodes := completionNodes(root)
+	fmt.Fprintf(bw, "#compdef %s\n\n%s() {\n", prog, fn)
+	fmt.Fprintf(bw, `    local cur path w i flag values pre v
+    local -a plain withvalue cands
+    cur="${words[CURRENT]}"
+    path="/"
+    for ((i=2; i<CURRENT; i++)); do
+        w="${words[i]}"
+        case "$path $w" in
+`)
+	writeTransitions(bw, nodes, "        ")
+	fmt.Fprintf(bw, `        esac
+    done
+    if [[ "$cur" == -*=* ]]; then
+        flag="${cur%%%%=*}"
+        flag="${flag#-}"
+        flag="${flag#-}"
+        values=""
+        case "$path $flag" in
+`)
+	writeValueCases(bw, nodes, "        ")
+	fmt.Fprintf(bw, `        esac
+        pre="${cur%%${cur##*[=,]}}"
+        for v in ${=values}; do
+            cands+=("$pre$v")
+        done
+        compadd -S '' -- $cands
+        return
+    fi
+    case "$path" in
+`)
+	for _, n := range nodes {
+		plain, withValue := n.words()
+		fmt.Fprintf(bw, "        \"%s\")\n            plain=(%s)\n            withvalue=(%s)\n            ;;\n", n.key, strings.Join(plain, " "), strings.Join(withValue, " "))
+	}
+	fmt.Fprintf(bw, `    esac
+    compadd -- $plain
+    compadd -S '' -- $withvalue
+}
+
+compdef %s %s
+`, fn, prog)
+	return bw.Flush()
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(s) + "'"
+}
+
+func (f *FlagSet) GenFishCompletion(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	root := f.Root()
+	prog := root.ProgramName()
+	fn := "_" + completionFunction(prog) + "_path"
+	nodes := completionNodes(root)
+	fmt.Fprintf(bw, "# fish completion for %s\n\nfunction %s\n", prog, fn)
+	fmt.Fprintf(bw, "    set -l path /\n    for w in (commandline -opc)[2..-1]\n        switch \"$path $w\"\n")
+	for _, n := range nodes {
+		for _, k := range n.keywords {
+			sub := n.set.subSets[k]
+			for _, a := range append([]string{k}, sub.keywordAliases...) {
+				fmt.Fprintf(bw, "            case %s\n                set path %s\n", fishQuote(n.key+" "+a), fishQuote(completionKey(sub)))
+			}
+		}
+	}
+	fmt.Fprintf(bw, "        end\n    end\n    echo $path\nend\n\ncomplete -c %s -f\n", prog)
+	for _, n := range nodes {
+		cond := fishQuote(fmt.Sprintf("test (%s) = %s", fn, n.key))
+		for _, k := range n.keywords {
+			fmt.Fprintf(bw, "complete -c %s -n %s -a %s -d %s\n", prog, cond, fishQuote(k), fishQuote(n.set.subSets[k].usage))
+		}
+		for _, fl := range n.flags {
+			opt := ""
+			switch {
+			case len(fl.values) > 0:
+				opt = " -x -a " + fishQuote(strings.Join(fl.values, " "))
+			case fl.takesValue:
+				opt = " -r"
+			}
+			fmt.Fprintf(bw, "complete -c %s -n %s -o %s%s -d %s\n", prog, cond, fishQuote(fl.name), opt, fishQuote(fl.usage))
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,95 @@
+package gu_flag
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func completionTree() *FlagSet {
+	root := NewRoot("prog", "")
+	root.Flags.Bool("verbose", false, "")
+	hat := root.NewFlagSet("hat", "set hat characteristics", "cap")
+	hat.Flags.String("style", "", "")
+	hat.ConstrainedSet("heels", nil, []string{"low", "high"}, "", false)
+	hat.NewFlagSet("color", "")
+	hat.NewFlagSet("secret", "").Hidden = true
+	root.NewFlagSet("shoes", "")
+	return root
+}
+
+// bashComplete runs the generated bash completion for the given words,
+// the last one being completed
+func bashComplete(t *testing.T, script string, words ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "completion.bash")
+	if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	quoted := []string{}
+	for _, w := range words {
+		quoted = append(quoted, "'"+w+"'")
+	}
+	cmd := exec.Command("bash", "-c", `source "$1"; COMP_WORDS=(`+strings.Join(quoted, " ")+`); COMP_CWORD=$((${#COMP_WORDS[@]}-1)); `+completionFunction("prog")+`; echo "${COMPREPLY[*]}"`, "-", path)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestBashCompletion(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	var script bytes.Buffer
+	if err := completionTree().GenBashCompletion(&script); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"prog", ""}, "hat shoes -verbose"},
+		{[]string{"prog", "h"}, "hat"},
+		{[]string{"prog", "hat", ""}, "color -heels= -style="},
+		{[]string{"prog", "cap", "-s"}, "-style="},
+		{[]string{"prog", "hat", "-heels=h"}, "high"},
+		{[]string{"prog", "hat", "-heels=low,"}, "low,low low,high"},
+		{[]string{"prog", "-verbose", "hat", "color", ""}, ""},
+	}
+	for _, c := range cases {
+		if got := bashComplete(t, script.String(), c.words...); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.words, got, c.want)
+		}
+	}
+}
+
+func TestZshAndFishCompletion(t *testing.T) {
+	var zsh, fish bytes.Buffer
+	root := completionTree()
+	if err := root.GenZshCompletion(&zsh); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.GenFishCompletion(&fish); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"#compdef prog", `"/ cap") path="/hat" ;;`, `"/hat heels") values="low high" ;;`} {
+		if !strings.Contains(zsh.String(), s) {
+			t.Errorf("%q not in zsh completion", s)
+		}
+	}
+	for _, s := range []string{"complete -c prog", "'set hat characteristics'"} {
+		if !strings.Contains(fish.String(), s) {
+			t.Errorf("%q not in fish completion", s)
+		}
+	}
+	for _, script := range []string{zsh.String(), fish.String()} {
+		if strings.Contains(script, "secret") {
+			t.Errorf("hidden keyword completed")
+		}
+	}
+}
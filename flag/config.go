@@ -0,0 +1,214 @@
+// Configuration files provide defaults for the whole keyword tree: a
+// JSON object holds the values of the flags of a subgrammar and, under
+// the name of each keyword, the object for the subgrammar it introduces.
+// Their values are used only for flags not given on the command line nor
+// through the environment.
+//
+// Configurations are layered: every LoadConfig or SetConfig adds a layer
+// to its FlagSet, and each flag takes its value from the first layer
+// giving it, looking first at the layers of the keyword defining the
+// flag, the last added first, then at the layers of the keywords above,
+// from the nearest up to the root. A layer gives a repeatable flag all
+// of its values, never merged with the values of other layers
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maxcalandrelli/goutil/encoding/json"
+)
+
+var (
+	UNKNOWN_CONFIG_KEY error = errors.New("unknown configuration key")
+	NOT_A_KEYWORD      error = errors.New("object given for a flag")
+	NOT_REPEATABLE     error = errors.New("multiple values given for a single-valued flag")
+	BAD_CONFIG_VALUE   error = errors.New("unsupported configuration value")
+)
+
+type ConfigError struct {
+	Path []string
+	Key  string
+	Err  error
+}
+
+func (ce *ConfigError) Error() string {
+	key := strings.Join(append(append([]string{}, ce.Path...), ce.Key), ".")
+	return fmt.Sprintf("configuration key '%s': %v", key, ce.Err)
+}
+
+func (ce *ConfigError) Unwrap() error {
+	return ce.Err
+}
+
+// LoadConfig reads a JSON configuration file for this FlagSet and the
+// subgrammars below it, as a layer above the ones already loaded
+func (f *FlagSet) LoadConfig(path string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	data, err := gu_json.JSONData{}.GetData(fd, gu_json.BUILT_ARRAY_NAME)
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s: %v", path, err))
+	}
+	return f.SetConfig(data)
+}
+
+// SetConfig checks data against the grammar and adds it as the topmost
+// configuration layer for this FlagSet and the subgrammars below it
+func (f *FlagSet) SetConfig(data gu_json.JSONData) error {
+	if err := f.checkConfig(data); err != nil {
+		return err
+	}
+	f.configs = append(f.configs, data)
+	return nil
+}
+
+// ClearConfig drops the configuration layers added to this FlagSet
+func (f *FlagSet) ClearConfig() {
+	f.configs = nil
+}
+
+func sortedKeys(data map[string]interface{}) []string {
+	r := []string{}
+	for k, _ := range data {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
+
+func (f *FlagSet) checkConfig(data map[string]interface{}) error {
+	for _, k := range sortedKeys(data) {
+		v := data[k]
+		if sub, ok := f.subSets[k]; ok {
+			section, isObject := v.(map[string]interface{})
+			if !isObject {
+				return &ConfigError{Path: f.Path(), Key: k, Err: BAD_CONFIG_VALUE}
+			}
+			if err := sub.checkConfig(section); err != nil {
+				return err
+			}
+			continue
+		}
+		fl, _ := f.lookup(k)
+		if fl == nil {
+			return &ConfigError{Path: f.Path(), Key: k, Err: UNKNOWN_CONFIG_KEY}
+		}
+		switch v.(type) {
+		case map[string]interface{}:
+			if !isKeyValue(fl.Value) {
+				return &ConfigError{Path: f.Path(), Key: k, Err: NOT_A_KEYWORD}
+			}
+		case []interface{}:
+			if _, repeatable := fl.Value.(RepeatableArg); !repeatable || isEnum(fl.Value) {
+				return &ConfigError{Path: f.Path(), Key: k, Err: NOT_REPEATABLE}
+			}
+		}
+	}
+	return nil
+}
+
+// configSections returns the parts of the configuration layers
+// pertaining to this subgrammar, from the one taking precedence
+func (f *FlagSet) configSections() []map[string]interface{} {
+	r := []map[string]interface{}{}
+	path := []string{}
+	for s := f; s != nil; s = s.parent {
+		for i := len(s.configs) - 1; i >= 0; i-- {
+			if section := configSection(s.configs[i], path); section != nil {
+				r = append(r, section)
+			}
+		}
+		path = append([]string{s.subCommand}, path...)
+	}
+	return r
+}
+
+func configSection(data gu_json.JSONData, path []string) map[string]interface{} {
+	section := map[string]interface{}(data)
+	for _, k := range path {
+		next, ok := section[k].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		section = next
+	}
+	return section
+}
+
+func configString(v interface{}) (string, bool) {
+	switch v.(type) {
+	case string:
+		return v.(string), true
+	case bool:
+		return strconv.FormatBool(v.(bool)), true
+	case float64:
+		return gu_json.DisplayString(v), true
+	}
+	return "", false
+}
+
+func (f *FlagSet) applyConfig() error {
+	section := map[string]interface{}{}
+	sections := f.configSections()
+	for i := len(sections) - 1; i >= 0; i-- {
+		for k, v := range sections[i] {
+			section[k] = v
+		}
+	}
+	for _, k := range sortedKeys(section) {
+		if _, isKeyword := f.subSets[k]; isKeyword || f.isSet(k) {
+			continue
+		}
+		values := []interface{}{section[k]}
+		switch section[k].(type) {
+		case []interface{}:
+			values = section[k].([]interface{})
+		case map[string]interface{}:
+			values = keyValues(section[k].(map[string]interface{}))
+		}
+		strs := []string{}
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			s, ok := configString(v)
+			if !ok {
+				return &ConfigError{Path: f.Path(), Key: k, Err: BAD_CONFIG_VALUE}
+			}
+			strs = append(strs, s)
+		}
+		if len(strs) == 0 {
+			continue
+		}
+		if err := f.setFromSource(k, k, OriginConfigFile, strs...); err != nil {
+			return &ConfigError{Path: f.Path(), Key: k, Err: err}
+		}
+	}
+	return nil
+}
+
+func isKeyValue(v interface{}) bool {
+	tla, ok := v.(*typedListArg)
+	return ok && tla.keyValue
+}
+
+// keyValues turns an object given for a map flag into key=value elements
+func keyValues(m map[string]interface{}) []interface{} {
+	r := []interface{}{}
+	for _, k := range sortedKeys(m) {
+		if s, ok := configString(m[k]); ok {
+			r = append(r, k+"="+s)
+		} else {
+			r = append(r, m[k])
+		}
+	}
+	return r
+}
@@ -0,0 +1,107 @@
+package gu_flag
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/maxcalandrelli/goutil/encoding/json"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func configTree() (*FlagSet, *string, *int, *[]string) {
+	root := NewRoot("prog", "")
+	hat := root.NewFlagSet("hat", "")
+	style := hat.Flags.String("style", "plain", "")
+	color := hat.NewFlagSet("color", "")
+	index := color.Flags.Int("index", -1, "")
+	shade := color.List("shade", []string{"dark"}, "")
+	return root, style, index, shade.(*listOrSetArg).values
+}
+
+func TestConfigNesting(t *testing.T) {
+	root, style, index, shade := configTree()
+	err := root.LoadConfig(writeConfig(t, `{"hat": {"style": "x", "color": {"index": 4, "shade": ["a", "b"]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Parse([]string{"hat", "color"}); err != nil {
+		t.Fatal(err)
+	}
+	if *style != "x" || *index != 4 || !reflect.DeepEqual(*shade, []string{"a", "b"}) {
+		t.Errorf("got style=%q index=%d shade=%v", *style, *index, *shade)
+	}
+	if o := root.subSets["hat"].Origin("style"); o != OriginConfigFile {
+		t.Errorf("origin of -style is %v", o)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	root, style, index, _ := configTree()
+	root.BindEnv("")
+	if err := root.SetConfig(gu_json.JSONData{"hat": map[string]interface{}{"style": "config",
+		"color": map[string]interface{}{"index": 1.0}}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HAT_COLOR_INDEX", "2")
+	if err := root.Parse([]string{"hat", "-style=cmdline", "color"}); err != nil {
+		t.Fatal(err)
+	}
+	if *style != "cmdline" || *index != 2 {
+		t.Errorf("got style=%q index=%d", *style, *index)
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	root, style, index, shade := configTree()
+	root.SetConfig(gu_json.JSONData{"hat": map[string]interface{}{"style": "base",
+		"color": map[string]interface{}{"index": 1.0, "shade": []interface{}{"a", "b"}}}})
+	root.SetConfig(gu_json.JSONData{"hat": map[string]interface{}{"style": "user"}})
+	color := root.subSets["hat"].subSets["color"]
+	color.SetConfig(gu_json.JSONData{"shade": []interface{}{"c"}})
+	if err := root.Parse([]string{"hat", "color"}); err != nil {
+		t.Fatal(err)
+	}
+	if *style != "user" || *index != 1 || !reflect.DeepEqual(*shade, []string{"c"}) {
+		t.Errorf("got style=%q index=%d shade=%v", *style, *index, *shade)
+	}
+	root.ClearConfig()
+	color.ClearConfig()
+	root.Reset()
+	if err := root.Parse([]string{"hat", "color"}); err != nil {
+		t.Fatal(err)
+	}
+	if *style != "plain" || *index != -1 {
+		t.Errorf("after ClearConfig got style=%q index=%d", *style, *index)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	cases := []struct {
+		data gu_json.JSONData
+		err  error
+	}{
+		{gu_json.JSONData{"nosuch": 1.0}, UNKNOWN_CONFIG_KEY},
+		{gu_json.JSONData{"hat": map[string]interface{}{"style": []interface{}{"a"}}}, NOT_REPEATABLE},
+		{gu_json.JSONData{"hat": map[string]interface{}{"style": map[string]interface{}{}}}, NOT_A_KEYWORD},
+		{gu_json.JSONData{"hat": "x"}, BAD_CONFIG_VALUE},
+	}
+	for _, c := range cases {
+		root, _, _, _ := configTree()
+		err := root.SetConfig(c.data)
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Err != c.err {
+			t.Errorf("%v: got %v, want %v", c.data, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Constraints on the flags of a subgrammar are checked once the whole
+// command line has been parsed, before running the HookFunc, for every
+// keyword along the selected path; all the violations are reported
+// together
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type constraintKind int
+
+const (
+	requiredFlag constraintKind = iota
+	exactlyOneOf
+	atMostOneOf
+	dependentFlags
+)
+
+type constraint struct {
+	kind  constraintKind
+	flags []string
+}
+
+type ConstraintError struct {
+	Violations []string
+}
+
+func (ce *ConstraintError) Error() string {
+	return strings.Join(ce.Violations, "; ")
+}
+
+// checkFlagNames verifies that the flags named in a constraint are
+// already defined, here or as persistent flags above
+func (f *FlagSet) checkFlagNames(names []string) error {
+	for _, n := range names {
+		if fl, _ := f.lookup(n); fl == nil {
+			return errors.New(fmt.Sprintf("no such flag -%s", n))
+		}
+	}
+	return nil
+}
+
+func (f *FlagSet) addConstraint(kind constraintKind, names []string) error {
+	if err := f.checkFlagNames(names); err != nil {
+		return err
+	}
+	f.constraints = append(f.constraints, constraint{kind: kind, flags: names})
+	return nil
+}
+
+// Require makes the named flags mandatory; like the other constraints,
+// it fails if any of the flags is not defined yet
+func (f *FlagSet) Require(names ...string) error {
+	if err := f.checkFlagNames(names); err != nil {
+		return err
+	}
+	for _, n := range names {
+		f.addConstraint(requiredFlag, []string{n})
+	}
+	return nil
+}
+
+func (f *FlagSet) ExactlyOneOf(names ...string) error {
+	return f.addConstraint(exactlyOneOf, names)
+}
+
+func (f *FlagSet) AtMostOneOf(names ...string) error {
+	return f.addConstraint(atMostOneOf, names)
+}
+
+// Requires states that when name is given, all the others must be given
+// as well
+func (f *FlagSet) Requires(name string, others ...string) error {
+	return f.addConstraint(dependentFlags, append([]string{name}, others...))
+}
+
+func (f *FlagSet) isRequired(name string) bool {
+	for _, c := range f.constraints {
+		if c.kind == requiredFlag && c.flags[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+func flagList(names []string) string {
+	return "-" + strings.Join(names, ", -")
+}
+
+func (c constraint) String() string {
+	switch c.kind {
+	case requiredFlag:
+		return fmt.Sprintf("-%s is required", c.flags[0])
+	case exactlyOneOf:
+		return fmt.Sprintf("exactly one of %s", flagList(c.flags))
+	case atMostOneOf:
+		return fmt.Sprintf("at most one of %s", flagList(c.flags))
+	}
+	return fmt.Sprintf("-%s requires %s", c.flags[0], flagList(c.flags[1:]))
+}
+
+func (f *FlagSet) countSet(names []string) (set, unset []string) {
+	for _, n := range names {
+		if f.isSet(n) {
+			set = append(set, n)
+		} else {
+			unset = append(unset, n)
+		}
+	}
+	return
+}
+
+func (f *FlagSet) violations() []string {
+	r := []string{}
+	where := ""
+	if path := f.Path(); len(path) > 0 {
+		where = strings.Join(path, " ") + ": "
+	}
+	for _, c := range f.constraints {
+		set, _ := f.countSet(c.flags)
+		msg := ""
+		switch c.kind {
+		case requiredFlag:
+			if len(set) == 0 {
+				msg = fmt.Sprintf("missing required flag -%s", c.flags[0])
+			}
+		case exactlyOneOf:
+			if len(set) != 1 {
+				msg = fmt.Sprintf("exactly one of %s must be given", flagList(c.flags))
+			}
+		case atMostOneOf:
+			if len(set) > 1 {
+				msg = fmt.Sprintf("%s cannot be given together", flagList(set))
+			}
+		case dependentFlags:
+			if f.isSet(c.flags[0]) {
+				if _, missing := f.countSet(c.flags[1:]); len(missing) > 0 {
+					msg = fmt.Sprintf("-%s requires %s", c.flags[0], flagList(missing))
+				}
+			}
+		}
+		if len(msg) > 0 {
+			r = append(r, where+msg)
+		}
+	}
+	return r
+}
+
+// checkConstraints validates the constraints of all the keywords from
+// the root down to this subgrammar
+func (f *FlagSet) checkConstraints() error {
+	ce := &ConstraintError{}
+	for _, s := range f.lineage() {
+		ce.Violations = append(ce.Violations, s.violations()...)
+	}
+	if len(ce.Violations) > 0 {
+		return ce
+	}
+	return nil
+}
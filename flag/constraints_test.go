@@ -0,0 +1,80 @@
+package gu_flag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func constraintTree(t *testing.T) *FlagSet {
+	t.Helper()
+	root := NewRoot("prog", "")
+	root.Flags.String("user", "", "")
+	root.Persist("user")
+	cmd := root.NewFlagSet("cmd", "")
+	for _, n := range []string{"a", "b", "c", "d"} {
+		cmd.Flags.String(n, "", "")
+	}
+	for _, err := range []error{
+		cmd.Require("a"),
+		cmd.ExactlyOneOf("b", "c"),
+		cmd.AtMostOneOf("c", "d"),
+		cmd.Requires("d", "user"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestConstraints(t *testing.T) {
+	cases := []struct {
+		args       string
+		violations []string
+	}{
+		{"cmd -a=1 -b=1", nil},
+		{"cmd -b=1", []string{"missing required flag -a"}},
+		{"cmd -a=1", []string{"exactly one of -b, -c must be given"}},
+		{"cmd -a=1 -c=1 -d=1", []string{"-c, -d cannot be given together", "-d requires -user"}},
+		{"cmd -a=1 -c=1 -d=1 -user=me", []string{"-c, -d cannot be given together"}},
+	}
+	for _, c := range cases {
+		err := constraintTree(t).Parse(strings.Fields(c.args))
+		if c.violations == nil {
+			if err != nil {
+				t.Errorf("%s: %v", c.args, err)
+			}
+			continue
+		}
+		var ce *ConstraintError
+		if !errors.As(err, &ce) {
+			t.Errorf("%s: got %v", c.args, err)
+			continue
+		}
+		for i, v := range c.violations {
+			c.violations[i] = "cmd: " + v
+		}
+		if strings.Join(ce.Violations, "|") != strings.Join(c.violations, "|") {
+			t.Errorf("%s: got %q, want %q", c.args, ce.Violations, c.violations)
+		}
+	}
+}
+
+func TestConstraintUnknownFlag(t *testing.T) {
+	root := NewRoot("prog", "")
+	root.Flags.String("a", "", "")
+	for _, err := range []error{
+		root.Require("typo"),
+		root.ExactlyOneOf("a", "typo"),
+		root.AtMostOneOf("typo", "a"),
+		root.Requires("a", "typo"),
+	} {
+		if err == nil || !strings.Contains(err.Error(), "-typo") {
+			t.Errorf("got %v", err)
+		}
+	}
+	if len(root.constraints) != 0 {
+		t.Errorf("constraints registered anyway: %v", root.constraints)
+	}
+}
@@ -0,0 +1,280 @@
+// A whole keyword tree can be described by nested structs: fields tagged
+// with flag become flags, fields tagged with arg positional args, and
+// struct fields tagged with cmd open the subgrammar of a keyword. A
+// command struct having a Run(*FlagSet) error method gets it as HookFunc.
+//
+//	type Shoes struct {
+//		Size  int      `flag:"size" default:"38" usage:"shoe size"`
+//		Heels []string `flag:"heels" set:"high,veryhigh" icase:"true"`
+//	}
+//	type Options struct {
+//		Shoes Shoes `cmd:"shoes" usage:"set shoes size"`
+//	}
+//
+// Other recognized tags are: short (single letter alias), required,
+// persistent, unique (slices as sets), sep (separator of repeatable
+// args), dupkey (error, replace or keep, for maps), aliases (of
+// keywords), min, max, optional and exists (for positional args). Rate
+// fields take rates, as in 10MB/s
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type runner interface {
+	Run(*FlagSet) error
+}
+
+// Define builds flags, positional args and keywords below this FlagSet
+// from the struct pointed to by spec
+func (f *FlagSet) Define(spec interface{}) error {
+	v := reflect.ValueOf(spec)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New(fmt.Sprintf("Define: %T is not a pointer to struct", spec))
+	}
+	if r, ok := spec.(runner); ok {
+		f.HookFunc = r.Run
+	}
+	return f.defineFields(v.Elem())
+}
+
+func tagList(tag string) []string {
+	if len(tag) == 0 {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+func tagBool(tag reflect.StructTag, key string) bool {
+	b, _ := strconv.ParseBool(tag.Get(key))
+	return b
+}
+
+func (f *FlagSet) defineFields(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 && !field.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		tag := field.Tag
+		var err error
+		switch {
+		case len(tag.Get("cmd")) > 0:
+			err = f.defineCommand(fv, tag)
+		case len(tag.Get("flag")) > 0:
+			err = f.defineFlag(fv, tag)
+		case len(tag.Get("arg")) > 0:
+			err = f.defineArg(fv, tag)
+		case field.Anonymous && fv.Kind() == reflect.Struct:
+			err = f.defineFields(fv)
+		}
+		if err != nil {
+			return errors.New(fmt.Sprintf("field %s: %v", field.Name, err))
+		}
+	}
+	return nil
+}
+
+func (f *FlagSet) defineCommand(fv reflect.Value, tag reflect.StructTag) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+	if fv.Kind() != reflect.Struct {
+		return errors.New("cmd tag on a non struct field")
+	}
+	sub := f.NewFlagSet(tag.Get("cmd"), tag.Get("usage"), tagList(tag.Get("aliases"))...)
+	return sub.Define(fv.Addr().Interface())
+}
+
+func (f *FlagSet) defineFlag(fv reflect.Value, tag reflect.StructTag) error {
+	name := tag.Get("flag")
+	usage := tag.Get("usage")
+	def := tag.Get("default")
+	p := fv.Addr().Interface()
+	var ra RepeatableArg
+	var err error
+	switch p.(type) {
+	case *bool:
+		b := false
+		if len(def) > 0 {
+			b, err = strconv.ParseBool(def)
+		}
+		f.Flags.BoolVar(p.(*bool), name, b, usage)
+	case *string:
+		f.Flags.StringVar(p.(*string), name, def, usage)
+	case *int:
+		n := int64(0)
+		if len(def) > 0 {
+			n, err = strconv.ParseInt(def, 0, 0)
+		}
+		f.Flags.IntVar(p.(*int), name, int(n), usage)
+	case *int64:
+		n := int64(0)
+		if len(def) > 0 {
+			n, err = strconv.ParseInt(def, 0, 64)
+		}
+		f.Flags.Int64Var(p.(*int64), name, n, usage)
+	case *uint:
+		n := uint64(0)
+		if len(def) > 0 {
+			n, err = strconv.ParseUint(def, 0, 0)
+		}
+		f.Flags.UintVar(p.(*uint), name, uint(n), usage)
+	case *uint64:
+		n := uint64(0)
+		if len(def) > 0 {
+			n, err = strconv.ParseUint(def, 0, 64)
+		}
+		f.Flags.Uint64Var(p.(*uint64), name, n, usage)
+	case *float64:
+		x := 0.0
+		if len(def) > 0 {
+			x, err = strconv.ParseFloat(def, 64)
+		}
+		f.Flags.Float64Var(p.(*float64), name, x, usage)
+	case *time.Duration:
+		d := time.Duration(0)
+		if len(def) > 0 {
+			d, err = time.ParseDuration(def)
+		}
+		f.Flags.DurationVar(p.(*time.Duration), name, d, usage)
+	case *Rate:
+		if len(def) == 0 {
+			def = "0"
+		}
+		if _, err = ParseRate(def); err == nil {
+			f.RateVar(p.(*Rate), name, def, usage)
+		}
+	case *[]string:
+		ra = f.defineStrings(p.(*[]string), name, tagList(def), usage, tag)
+	case *[]int:
+		ints := []int{}
+		for _, s := range tagList(def) {
+			n, e := strconv.Atoi(s)
+			if e != nil {
+				err = e
+			}
+			ints = append(ints, n)
+		}
+		ra = f.IntListVar(p.(*[]int), name, ints, usage)
+	case *[]float64:
+		floats := []float64{}
+		for _, s := range tagList(def) {
+			x, e := strconv.ParseFloat(s, 64)
+			if e != nil {
+				err = e
+			}
+			floats = append(floats, x)
+		}
+		ra = f.FloatListVar(p.(*[]float64), name, floats, usage)
+	case *[]time.Duration:
+		durations := []time.Duration{}
+		for _, s := range tagList(def) {
+			d, e := time.ParseDuration(s)
+			if e != nil {
+				err = e
+			}
+			durations = append(durations, d)
+		}
+		ra = f.DurationListVar(p.(*[]time.Duration), name, durations, usage)
+	case *map[string]string:
+		m := map[string]string{}
+		for _, s := range tagList(def) {
+			kv := strings.SplitN(s, "=", 2)
+			if len(kv) != 2 {
+				err = NOT_KEY_VALUE
+				continue
+			}
+			m[kv[0]] = kv[1]
+		}
+		policy := map[string]DuplicateKeyPolicy{"": DuplicateKeyError, "error": DuplicateKeyError, "replace": DuplicateKeyReplace, "keep": DuplicateKeyKeepFirst}
+		dupkey, known := policy[tag.Get("dupkey")]
+		if !known {
+			err = errors.New(fmt.Sprintf("unknown dupkey policy %q", tag.Get("dupkey")))
+		}
+		ra = f.MapVar(p.(*map[string]string), name, m, usage, dupkey)
+	default:
+		return errors.New(fmt.Sprintf("unsupported flag type %s", fv.Type()))
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("bad default %q: %v", def, err))
+	}
+	if sep := tag.Get("sep"); len(sep) > 0 && ra != nil {
+		ra.SetSeparator(sep)
+	}
+	if short := tag.Get("short"); len(short) > 0 {
+		if err := f.Alias(short, name); err != nil {
+			return err
+		}
+	}
+	if tagBool(tag, "required") {
+		if err := f.Require(name); err != nil {
+			return err
+		}
+	}
+	if tagBool(tag, "persistent") {
+		f.Persist(name)
+	}
+	return nil
+}
+
+// defineStrings maps string slices to lists, to sets when unique or to
+// constrained sets when the allowed values are given
+func (f *FlagSet) defineStrings(p *[]string, name string, value []string, usage string, tag reflect.StructTag) RepeatableArg {
+	icase := tagBool(tag, "icase")
+	switch {
+	case len(tag.Get("set")) > 0:
+		return f.ConstrainedSetVar(p, name, value, tagList(tag.Get("set")), usage, icase)
+	case tagBool(tag, "unique") || icase:
+		return f.SetVar(p, name, value, usage, icase)
+	}
+	return f.ListVar(p, name, value, usage)
+}
+
+func (f *FlagSet) defineArg(fv reflect.Value, tag reflect.StructTag) error {
+	name := tag.Get("arg")
+	usage := tag.Get("usage")
+	min, max := 1, -1
+	var err error
+	if s := tag.Get("min"); len(s) > 0 {
+		if min, err = strconv.Atoi(s); err != nil {
+			return err
+		}
+	}
+	if s := tag.Get("max"); len(s) > 0 {
+		if max, err = strconv.Atoi(s); err != nil {
+			return err
+		}
+	}
+	var pa *PositionalArg
+	switch p := fv.Addr().Interface().(type) {
+	case *string:
+		pa = f.StringArg(p, name, usage)
+	case *int:
+		pa = f.IntArg(p, name, usage)
+	case *[]string:
+		pa = f.StringsArg(p, name, usage, min, max)
+	case *[]int:
+		pa = f.IntsArg(p, name, usage, min, max)
+	default:
+		return errors.New(fmt.Sprintf("unsupported arg type %s", fv.Type()))
+	}
+	if tagBool(tag, "optional") {
+		pa.Optional()
+	}
+	if tagBool(tag, "exists") {
+		pa.Validate(FileExists)
+	}
+	return nil
+}
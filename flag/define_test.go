@@ -0,0 +1,111 @@
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type defineShoes struct {
+	Size   int               `flag:"size" default:"38" usage:"shoe size" short:"s"`
+	Heels  []string          `flag:"heels" set:"high,veryhigh" icase:"true"`
+	Laces  []string          `flag:"laces" sep:";"`
+	Tags   map[string]string `flag:"tag" dupkey:"replace"`
+	Delays []time.Duration   `flag:"delay" default:"1s,2s"`
+	Speed  Rate              `flag:"speed" default:"10MB/s"`
+	Files  []string          `arg:"file" min:"1" max:"2"`
+	ran    bool
+}
+
+func (s *defineShoes) Run(*FlagSet) error {
+	s.ran = true
+	return nil
+}
+
+type defineOptions struct {
+	Verbose bool         `flag:"verbose" persistent:"true"`
+	Name    string       `flag:"name" required:"true"`
+	Shoes   defineShoes  `cmd:"shoes" usage:"set shoes size" aliases:"sh"`
+	Hat     *struct{}    `cmd:"hat"`
+	Copy    defineCopier `cmd:"copy"`
+}
+
+type defineCopier struct {
+	Source string `arg:"source" exists:"true"`
+	Target string `arg:"target" optional:"true"`
+}
+
+func defineTree(t *testing.T) (*FlagSet, *defineOptions) {
+	t.Helper()
+	o := &defineOptions{}
+	root := NewRoot("prog", "")
+	if err := root.Define(o); err != nil {
+		t.Fatal(err)
+	}
+	return root, o
+}
+
+func TestDefine(t *testing.T) {
+	root, o := defineTree(t)
+	if o.Hat == nil || strings.Join(root.Keywords(), ",") != "copy,hat,shoes" {
+		t.Errorf("got keywords %v", root.Keywords())
+	}
+	args := "-name=x sh -verbose -s=42 -heels=HIGH -laces=a;b -tag=a=1 -tag=a=2 -speed=1KB/s f1"
+	if err := root.Parse(strings.Fields(args)); err != nil {
+		t.Fatal(err)
+	}
+	s := o.Shoes
+	got := fmt.Sprint(o.Verbose, s.Size, s.Heels, s.Laces, s.Tags, s.Delays, s.Speed, s.Files, s.ran)
+	if want := "true 42 [high] [a b] map[a:2] [1s 2s] 1KB/s [f1] true"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	var pe *ParseError
+	root, _ = defineTree(t)
+	if err := root.Parse([]string{"shoes", "f1"}); !errors.As(err, &pe) || pe.Kind != ConstraintViolation {
+		t.Errorf("required flag: got %v", err)
+	}
+	root, _ = defineTree(t)
+	if err := root.Parse(strings.Fields("-name=x shoes f1 f2 f3")); !errors.As(err, &pe) || pe.Kind != UnexpectedPositional {
+		t.Errorf("max args: got %v", err)
+	}
+	existing := filepath.Join(t.TempDir(), "source")
+	os.WriteFile(existing, nil, 0644)
+	root, o = defineTree(t)
+	if err := root.Parse([]string{"-name=x", "copy", existing}); err != nil || o.Copy.Source != existing || o.Copy.Target != "" {
+		t.Errorf("got %v, %+v", err, o.Copy)
+	}
+	root, _ = defineTree(t)
+	if err := root.Parse([]string{"-name=x", "copy", existing + ".missing"}); err == nil {
+		t.Errorf("missing file accepted")
+	}
+}
+
+func TestDefineErrors(t *testing.T) {
+	cases := []interface{}{
+		defineOptions{},
+		&struct {
+			C chan int `flag:"c"`
+		}{},
+		&struct {
+			N int `flag:"n" default:"many"`
+		}{},
+		&struct {
+			M map[string]string `flag:"m" dupkey:"sometimes"`
+		}{},
+		&struct {
+			F float32 `arg:"f"`
+		}{},
+		&struct {
+			S string `cmd:"s"`
+		}{},
+	}
+	for _, spec := range cases {
+		if err := NewRoot("prog", "").Define(spec); err == nil {
+			t.Errorf("%T accepted", spec)
+		}
+	}
+}
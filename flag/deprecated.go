@@ -0,0 +1,233 @@
+// Flags and keywords can be hidden, so that they are still parsed but
+// no more shown in help, documentation and completion, or deprecated,
+// so that using them logs a warning until the program version in which
+// they are removed
+package gu_flag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/maxcalandrelli/goutil/log"
+)
+
+type Version struct {
+	Major int
+	Minor int
+}
+
+// Deprecation describes a deprecated flag or keyword; ReplacedBy names
+// the flag receiving the values given to a deprecated flag, or just the
+// keyword to use instead of a deprecated one. A zero RemovedIn means no
+// removal is planned
+type Deprecation struct {
+	Message    string
+	ReplacedBy string
+	RemovedIn  Version
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+func (v Version) isZero() bool {
+	return v.Major == 0 && v.Minor == 0
+}
+
+func (v Version) atLeast(o Version) bool {
+	return v.Major > o.Major || (v.Major == o.Major && v.Minor >= o.Minor)
+}
+
+// SetVersion sets the version of the program, checked against the
+// removal version of deprecated flags and keywords below this FlagSet
+func (f *FlagSet) SetVersion(major, minor int) {
+	f.version = Version{major, minor}
+}
+
+func (f *FlagSet) Version() Version {
+	for s := f; s != nil; s = s.parent {
+		if !s.version.isZero() {
+			return s.version
+		}
+	}
+	return Version{}
+}
+
+// SetLogger sets the logger receiving deprecation warnings; unless
+// overridden, it is inherited from the parent keyword and defaults to
+// gu_log.STD_LOGGER
+func (f *FlagSet) SetLogger(l gu_log.Logger) {
+	f.logger = l
+}
+
+func (f *FlagSet) Logger() gu_log.Logger {
+	for s := f; s != nil; s = s.parent {
+		if s.logger != nil {
+			return s.logger
+		}
+	}
+	return gu_log.STD_LOGGER
+}
+
+// HideFlags omits the named flags from help, documentation and
+// completion
+func (f *FlagSet) HideFlags(names ...string) {
+	if f.hidden == nil {
+		f.hidden = map[string]bool{}
+	}
+	for _, n := range names {
+		f.hidden[n] = true
+	}
+}
+
+// DeprecateFlag hides the named flag and warns whenever it is given on
+// the command line, forwarding its value to the replacement, if any
+func (f *FlagSet) DeprecateFlag(name string, d Deprecation) error {
+	if f.Flags.Lookup(name) == nil {
+		return errors.New(fmt.Sprintf("no such flag -%s", name))
+	}
+	if fl, _ := f.lookup(d.ReplacedBy); len(d.ReplacedBy) > 0 && fl == nil {
+		return errors.New(fmt.Sprintf("no such flag -%s", d.ReplacedBy))
+	}
+	if f.forwardsTo(d.ReplacedBy, f, f.canonicalFlag(name)) {
+		return errors.New(fmt.Sprintf("flag -%s would be forwarded back to itself through -%s", name, d.ReplacedBy))
+	}
+	if f.deprecated == nil {
+		f.deprecated = map[string]*Deprecation{}
+	}
+	f.deprecated[f.canonicalFlag(name)] = &d
+	return nil
+}
+
+// forwardsTo tells if a value given to the named flag ends up, following
+// the replacements of the deprecated flags, to the flag called target in
+// owner
+func (f *FlagSet) forwardsTo(name string, owner *FlagSet, target string) bool {
+	seen := map[*flag.Flag]bool{}
+	for s := f; len(name) > 0; {
+		fl, o := s.lookup(name)
+		if fl == nil {
+			return false
+		}
+		n := o.canonicalFlag(name)
+		if fl = o.Flags.Lookup(n); seen[fl] {
+			return false
+		}
+		seen[fl] = true
+		if o == owner && n == target {
+			return true
+		}
+		d := o.deprecated[n]
+		if d == nil {
+			return false
+		}
+		s, name = o, d.ReplacedBy
+	}
+	return false
+}
+
+// Deprecate hides the keyword of this FlagSet, and warns whenever it is
+// selected
+func (f *FlagSet) Deprecate(d Deprecation) {
+	f.deprecation = &d
+}
+
+func (f *FlagSet) isHiddenFlag(name string) bool {
+	o := f.owner(name)
+	n := o.canonicalFlag(name)
+	return o.hidden[n] || o.deprecated[n] != nil
+}
+
+// listed tells the flags to be shown in help, documentation and
+// completion
+func (f *FlagSet) listed(fl *flag.Flag) bool {
+	_, isAlias := f.owner(fl.Name).aliases[fl.Name]
+	return !isAlias && !f.isHiddenFlag(fl.Name)
+}
+
+func (f *FlagSet) isHiddenKeyword() bool {
+	return f.Hidden || f.deprecation != nil
+}
+
+// visibleKeywords returns the keywords neither hidden nor deprecated
+func (f *FlagSet) visibleKeywords() []string {
+	r := []string{}
+	for _, k := range f.Keywords() {
+		if !f.subSets[k].isHiddenKeyword() {
+			r = append(r, k)
+		}
+	}
+	return r
+}
+
+// walkVisible is like Walk, skipping hidden and deprecated keywords
+func (f *FlagSet) walkVisible(visit func(*FlagSet)) {
+	visit(f)
+	for _, k := range f.visibleKeywords() {
+		f.subSets[k].walkVisible(visit)
+	}
+}
+
+func (d *Deprecation) describe(instead string) string {
+	r := []string{}
+	if !d.RemovedIn.isZero() {
+		r = append(r, "removal planned in version "+d.RemovedIn.String())
+	}
+	if len(d.ReplacedBy) > 0 {
+		r = append(r, fmt.Sprintf("use %s instead", instead))
+	}
+	if len(d.Message) > 0 {
+		r = append(r, d.Message)
+	}
+	return strings.Join(r, "; ")
+}
+
+// deprecationCheck fails when the program version reached the removal
+// version of d, and warns otherwise
+func (f *FlagSet) deprecationCheck(d *Deprecation, what, instead string, pe *ParseError) error {
+	if v := f.Version(); !d.RemovedIn.isZero() && !v.isZero() && v.atLeast(d.RemovedIn) {
+		pe.Err = errors.New(fmt.Sprintf("%s was removed in version %s", what, d.RemovedIn))
+		if len(d.ReplacedBy) > 0 {
+			pe.Suggestions = []string{d.ReplacedBy}
+		}
+		return pe
+	}
+	msg := what + " is deprecated"
+	if details := d.describe(instead); len(details) > 0 {
+		msg += ": " + details
+	}
+	f.Logger().Warning("%s", msg)
+	return nil
+}
+
+func (f *FlagSet) checkDeprecatedFlag(token, name string) error {
+	o := f.owner(name)
+	d := o.deprecated[o.canonicalFlag(name)]
+	if d == nil {
+		return nil
+	}
+	pe := f.parseError(Removed, token, nil)
+	pe.Flag = name
+	return f.deprecationCheck(d, "flag -"+name, "-"+d.ReplacedBy, pe)
+}
+
+func (f *FlagSet) checkDeprecatedKeyword(token string) error {
+	if f.deprecation == nil {
+		return nil
+	}
+	pe := f.parent.parseError(Removed, token, nil)
+	return f.deprecationCheck(f.deprecation, fmt.Sprintf("keyword '%s'", token), "'"+f.deprecation.ReplacedBy+"'", pe)
+}
+
+// forwardDeprecated gives the value of a deprecated flag to its
+// replacement, looked up from the keyword defining the deprecated flag
+func (f *FlagSet) forwardDeprecated(token, name, value string, op byte) error {
+	o := f.owner(name)
+	d := o.deprecated[o.canonicalFlag(name)]
+	if d == nil || len(d.ReplacedBy) == 0 {
+		return nil
+	}
+	return o.setFlag(token, d.ReplacedBy, value, op)
+}
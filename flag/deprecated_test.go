@@ -0,0 +1,135 @@
+package gu_flag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/maxcalandrelli/goutil/encoding/json"
+	"github.com/maxcalandrelli/goutil/log"
+)
+
+type warningLogger struct {
+	warnings []string
+}
+
+func (wl *warningLogger) Info(msg string, args ...interface{}) {}
+func (wl *warningLogger) Warning(msg string, args ...interface{}) {
+	wl.warnings = append(wl.warnings, fmt.Sprintf(msg, args...))
+}
+func (wl *warningLogger) Error(msg string, args ...interface{})                         {}
+func (wl *warningLogger) Debug(msg string, args ...interface{})                         {}
+func (wl *warningLogger) Custom(level gu_log.LogLevel, msg string, args ...interface{}) {}
+func (wl *warningLogger) SetLevel(level gu_log.LogLevel)                                {}
+func (wl *warningLogger) GetCurrentLevel() gu_log.LogLevel                              { return 0 }
+func (wl *warningLogger) MessageId(id uint32) gu_log.Logger                             { return wl }
+
+func deprecatedTree(t *testing.T) (*FlagSet, *warningLogger) {
+	t.Helper()
+	root := NewRoot("prog", "")
+	wl := &warningLogger{}
+	root.SetLogger(wl)
+	root.SetVersion(2, 0)
+	root.Flags.String("colour", "", "")
+	root.Flags.String("color", "", "")
+	root.Flags.Bool("old", false, "")
+	root.Flags.Bool("secret", false, "")
+	root.HideFlags("secret")
+	for name, d := range map[string]Deprecation{
+		"colour": {ReplacedBy: "color"},
+		"old":    {RemovedIn: Version{2, 0}, Message: "no more needed"},
+	} {
+		if err := root.DeprecateFlag(name, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root.NewFlagSet("paint", "")
+	root.NewFlagSet("draw", "").Deprecate(Deprecation{ReplacedBy: "paint", RemovedIn: Version{3, 0}})
+	return root, wl
+}
+
+func TestDeprecatedFlags(t *testing.T) {
+	root, wl := deprecatedTree(t)
+	if err := root.Parse([]string{"-colour=red", "-secret", "draw"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := root.Value("color"); v != "red" {
+		t.Errorf("value not forwarded: %v", v)
+	}
+	if len(wl.warnings) != 2 || !strings.Contains(wl.warnings[0], "use -color instead") ||
+		!strings.Contains(wl.warnings[1], "removal planned in version 3.0") {
+		t.Errorf("got warnings %q", wl.warnings)
+	}
+	var pe *ParseError
+	if err := root.Parse([]string{"-old", "paint"}); !errors.As(err, &pe) || pe.Kind != Removed {
+		t.Errorf("got %v", err)
+	}
+	var out bytes.Buffer
+	root.WriteHelp(&out)
+	for _, hidden := range []string{"-colour", "-secret", "-old", "draw"} {
+		if strings.Contains(out.String(), hidden) {
+			t.Errorf("%s listed in help:\n%s", hidden, out.String())
+		}
+	}
+}
+
+func TestDeprecationCycles(t *testing.T) {
+	root := NewRoot("prog", "")
+	for _, n := range []string{"a", "b", "c"} {
+		root.Flags.String(n, "", "")
+	}
+	root.Alias("x", "a")
+	if err := root.DeprecateFlag("a", Deprecation{ReplacedBy: "x"}); err == nil {
+		t.Errorf("flag forwarded to its alias")
+	}
+	if err := root.DeprecateFlag("a", Deprecation{ReplacedBy: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.DeprecateFlag("b", Deprecation{ReplacedBy: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.DeprecateFlag("c", Deprecation{ReplacedBy: "a"}); err == nil {
+		t.Errorf("cycle a -> b -> c -> a accepted")
+	}
+	sub := root.NewFlagSet("sub", "")
+	sub.Flags.String("d", "", "")
+	root.Persist("a")
+	if err := sub.DeprecateFlag("d", Deprecation{ReplacedBy: "a"}); err != nil {
+		t.Errorf("chain through persistent flags rejected: %v", err)
+	}
+	root.SetLogger(gu_log.NIL_LOGGER)
+	if err := root.Parse([]string{"sub", "-d=v"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := root.Value("c"); v != "v" {
+		t.Errorf("value not forwarded along the chain: %v", v)
+	}
+}
+
+func TestDeprecatedFlagsFromSources(t *testing.T) {
+	root, wl := deprecatedTree(t)
+	root.BindEnv("P")
+	t.Setenv("P_COLOUR", "red")
+	if err := root.Parse([]string{"paint"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := root.Value("color"); v != "red" || root.Origin("color") != OriginEnvironment {
+		t.Errorf("value not forwarded: %v from %v", v, root.Origin("color"))
+	}
+	if len(wl.warnings) != 1 || !strings.Contains(wl.warnings[0], "use -color instead") {
+		t.Errorf("got warnings %q", wl.warnings)
+	}
+	root, _ = deprecatedTree(t)
+	root.BindEnv("P")
+	if err := root.Parse([]string{"-color=blue", "paint"}); err != nil || root.Value("color") != "blue" {
+		t.Errorf("command line overridden: %v %v", root.Value("color"), err)
+	}
+	root, _ = deprecatedTree(t)
+	root.SetConfig(gu_json.JSONData{"old": true})
+	var pe *ParseError
+	if err := root.Parse([]string{"paint"}); !errors.As(err, &pe) || pe.Kind != Removed {
+		t.Errorf("got %v", err)
+	}
+}
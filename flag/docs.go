@@ -0,0 +1,373 @@
+// Reference documentation, as roff man pages or Markdown, is generated
+// walking the keyword tree: either one page per keyword path or a single
+// page describing the whole tree
+package gu_flag
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManHeader holds the fields of the .TH line of the generated man pages;
+// Section defaults to 1 and Date to the current month
+type ManHeader struct {
+	Section string
+	Date    string
+	Source  string
+	Manual  string
+}
+
+type docFlag struct {
+	names []string
+	arg   string
+	usage string
+	notes []string
+}
+
+type docSection struct {
+	title string
+	flags []docFlag
+}
+
+func (f *FlagSet) docFlags(include func(*flag.Flag) bool) []docFlag {
+	r := []docFlag{}
+	f.visitAll(func(fl *flag.Flag) {
+		if !f.listed(fl) || !include(fl) {
+			return
+		}
+		o := f.owner(fl.Name)
+		df := docFlag{names: o.flagAliases(fl.Name)}
+		sort.Strings(df.names)
+		df.names = append(df.names, fl.Name)
+		df.arg, df.usage = flag.UnquoteUsage(fl)
+		if o.isRequired(fl.Name) {
+			df.notes = append(df.notes, "required")
+		} else if !isZeroDefault(fl.DefValue) {
+			df.notes = append(df.notes, "default: "+fl.DefValue)
+		}
+		if values := allowedValues(fl); len(values) > 0 {
+			df.notes = append(df.notes, "allowed values: "+strings.Join(values, ", "))
+		}
+		if ra, ok := fl.Value.(RepeatableArg); ok && !isEnum(fl.Value) {
+			df.notes = append(df.notes, fmt.Sprintf("repeatable, values separated by %q", ra.GetSeparator()))
+		}
+		r = append(r, df)
+	})
+	return r
+}
+
+// docSections groups the flags accepted at this keyword path the same
+// way as WriteHelp does
+func (f *FlagSet) docSections() []docSection {
+	r := []docSection{
+		{"Flags", f.docFlags(func(fl *flag.Flag) bool { return !f.isInherited(fl) })},
+		{"Persistent flags", f.docFlags(f.isInherited)},
+	}
+	for p := f.parent; p != nil; p = p.parent {
+		title := "Global flags"
+		if p.parent != nil {
+			title = fmt.Sprintf("Flags inherited from '%s'", strings.Join(p.Path(), " "))
+		}
+		r = append(r, docSection{title, p.docFlags(func(fl *flag.Flag) bool {
+			found, _ := f.lookup(fl.Name)
+			return found == nil
+		})})
+	}
+	return r
+}
+
+func (f *FlagSet) constraintsDoc() []string {
+	r := []string{}
+	for _, c := range f.constraints {
+		if c.kind != requiredFlag {
+			r = append(r, c.String())
+		}
+	}
+	return r
+}
+
+// commandName is the program name followed by the keyword path, joined
+// by sep
+func (f *FlagSet) commandName(sep string) string {
+	return strings.Join(append([]string{f.ProgramName()}, f.Path()...), sep)
+}
+
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = "\\&" + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// roffQuote makes s a single macro argument, double quoted
+func roffQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+	s = strings.ReplaceAll(s, "\"", "\\(dq")
+	return "\"" + strings.ReplaceAll(s, "\n", " ") + "\""
+}
+
+func roffFlag(name string) string {
+	return "\\fB\\-" + strings.ReplaceAll(roffEscape(name), "-", "\\-") + "\\fR"
+}
+
+func (f *FlagSet) writeManHeader(w io.Writer, header ManHeader) {
+	if len(header.Section) == 0 {
+		header.Section = "1"
+	}
+	if len(header.Date) == 0 {
+		header.Date = time.Now().Format("Jan 2006")
+	}
+	fields := []string{strings.ToUpper(f.commandName("-")), header.Section, header.Date, header.Source, header.Manual}
+	for i, field := range fields {
+		fields[i] = roffQuote(field)
+	}
+	fmt.Fprintf(w, ".TH %s\n", strings.Join(fields, " "))
+	fmt.Fprintf(w, ".SH NAME\n%s", roffEscape(f.commandName("-")))
+	if len(f.usage) > 0 {
+		fmt.Fprintf(w, " \\- %s", roffEscape(f.usage))
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// writeManBody writes the sections describing this keyword path, using
+// sh as the section macro
+func (f *FlagSet) writeManBody(w io.Writer, sh string, seeAlso func(*FlagSet) string) {
+	fmt.Fprintf(w, "%s SYNOPSIS\n.B %s\n", sh, roffEscape(f.UsageLine()))
+	if len(f.usage) > 0 {
+		fmt.Fprintf(w, "%s DESCRIPTION\n%s\n", sh, roffEscape(f.usage))
+	}
+	if kw := f.visibleKeywords(); len(kw) > 0 {
+		fmt.Fprintf(w, "%s KEYWORDS\n", sh)
+		for _, k := range kw {
+			sub := f.subSets[k]
+			fmt.Fprintf(w, ".TP\n\\fB%s\\fR", roffEscape(k))
+			if len(sub.keywordAliases) > 0 {
+				fmt.Fprintf(w, " (%s)", roffEscape(strings.Join(sub.keywordAliases, ", ")))
+			}
+			fmt.Fprintf(w, "\n%s\n", roffEscape(sub.usage))
+			if ref := seeAlso(sub); len(ref) > 0 {
+				fmt.Fprintf(w, "See %s.\n", ref)
+			}
+		}
+	}
+	if len(f.positionals) > 0 {
+		fmt.Fprintf(w, "%s ARGUMENTS\n", sh)
+		for _, pa := range f.positionals {
+			fmt.Fprintf(w, ".TP\n\\fI%s\\fR\n%s\n", roffEscape(pa.String()), roffEscape(pa.usage))
+		}
+	}
+	for _, ds := range f.docSections() {
+		if len(ds.flags) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s %s\n", sh, roffQuote(strings.ToUpper(ds.title)))
+		for _, df := range ds.flags {
+			names := []string{}
+			for _, n := range df.names {
+				names = append(names, roffFlag(n))
+			}
+			fmt.Fprintf(w, ".TP\n%s", strings.Join(names, ", "))
+			if len(df.arg) > 0 {
+				fmt.Fprintf(w, " \\fI%s\\fR", roffEscape(df.arg))
+			}
+			fmt.Fprintf(w, "\n%s\n", roffEscape(df.usage))
+			for _, n := range df.notes {
+				fmt.Fprintf(w, ".br\n%s\n", roffEscape(strings.ToUpper(n[:1])+n[1:]))
+			}
+		}
+	}
+	if groups := f.constraintsDoc(); len(groups) > 0 {
+		fmt.Fprintf(w, "%s CONSTRAINTS\n", sh)
+		for _, g := range groups {
+			fmt.Fprintf(w, ".PP\n%s\n", roffEscape(g))
+		}
+	}
+}
+
+func manReference(s *FlagSet, section string) string {
+	return fmt.Sprintf("\\fB%s\\fR(%s)", roffEscape(s.commandName("-")), section)
+}
+
+// GenMan writes the man page of this keyword path, referring to the
+// pages of the keywords around it
+func (f *FlagSet) GenMan(w io.Writer, header ManHeader) error {
+	bw := bufio.NewWriter(w)
+	f.writeManHeader(bw, header)
+	if len(header.Section) == 0 {
+		header.Section = "1"
+	}
+	ref := func(s *FlagSet) string { return manReference(s, header.Section) }
+	f.writeManBody(bw, ".SH", ref)
+	refs := []string{}
+	if f.parent != nil {
+		refs = append(refs, ref(f.parent))
+	}
+	for _, k := range f.visibleKeywords() {
+		refs = append(refs, ref(f.subSets[k]))
+	}
+	if len(refs) > 0 {
+		fmt.Fprintf(bw, ".SH \"SEE ALSO\"\n%s\n", strings.Join(refs, ", "))
+	}
+	return bw.Flush()
+}
+
+// GenManTree writes into dir one man page for every keyword path below
+// this one, named as in prog-keyword-subkeyword.1
+func (f *FlagSet) GenManTree(dir string, header ManHeader) error {
+	section := header.Section
+	if len(section) == 0 {
+		section = "1"
+	}
+	var err error
+	f.walkVisible(func(s *FlagSet) {
+		if err == nil {
+			err = writeDocFile(filepath.Join(dir, s.commandName("-")+"."+section), func(w io.Writer) error {
+				return s.GenMan(w, header)
+			})
+		}
+	})
+	return err
+}
+
+// GenManSingle writes a single man page describing the whole tree below
+// this keyword path, with a section for each keyword
+func (f *FlagSet) GenManSingle(w io.Writer, header ManHeader) error {
+	bw := bufio.NewWriter(w)
+	f.writeManHeader(bw, header)
+	noRef := func(*FlagSet) string { return "" }
+	f.writeManBody(bw, ".SH", noRef)
+	f.walkVisible(func(s *FlagSet) {
+		if s != f {
+			fmt.Fprintf(bw, ".SH %s\n", roffQuote(strings.ToUpper(s.commandName(" "))))
+			s.writeManBody(bw, ".SS", noRef)
+		}
+	})
+	return bw.Flush()
+}
+
+func markdownCode(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "'") + "`"
+}
+
+// writeMarkdownBody writes the sections describing this keyword path,
+// using heading as the prefix of their titles
+func (f *FlagSet) writeMarkdownBody(w io.Writer, heading string, link func(*FlagSet) string) {
+	fmt.Fprintf(w, "\n```\n%s\n```\n", f.UsageLine())
+	if len(f.usage) > 0 {
+		fmt.Fprintf(w, "\n%s\n", f.usage)
+	}
+	if kw := f.visibleKeywords(); len(kw) > 0 {
+		fmt.Fprintf(w, "\n%s Keywords\n\n", heading)
+		for _, k := range kw {
+			sub := f.subSets[k]
+			fmt.Fprintf(w, "- [%s](%s)", markdownCode(k), link(sub))
+			if len(sub.keywordAliases) > 0 {
+				fmt.Fprintf(w, " (%s)", markdownCode(strings.Join(sub.keywordAliases, ", ")))
+			}
+			fmt.Fprintf(w, ": %s\n", sub.usage)
+		}
+	}
+	if len(f.positionals) > 0 {
+		fmt.Fprintf(w, "\n%s Arguments\n\n", heading)
+		for _, pa := range f.positionals {
+			fmt.Fprintf(w, "- %s: %s\n", markdownCode(pa.String()), pa.usage)
+		}
+	}
+	for _, ds := range f.docSections() {
+		if len(ds.flags) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s %s\n\n", heading, ds.title)
+		for _, df := range ds.flags {
+			names := []string{}
+			for _, n := range df.names {
+				names = append(names, "-"+n)
+			}
+			syntax := strings.Join(names, ", ")
+			if len(df.arg) > 0 {
+				syntax += " " + df.arg
+			}
+			fmt.Fprintf(w, "- %s: %s", markdownCode(syntax), strings.ReplaceAll(df.usage, "\n", " "))
+			if len(df.notes) > 0 {
+				fmt.Fprintf(w, " (%s)", strings.Join(df.notes, "; "))
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+	if groups := f.constraintsDoc(); len(groups) > 0 {
+		fmt.Fprintf(w, "\n%s Constraints\n\n", heading)
+		for _, g := range groups {
+			fmt.Fprintf(w, "- %s\n", g)
+		}
+	}
+}
+
+func markdownFile(s *FlagSet) string {
+	return s.commandName("_") + ".md"
+}
+
+func markdownAnchor(s *FlagSet) string {
+	return "#" + s.commandName("-")
+}
+
+// GenMarkdown writes the Markdown reference of this keyword path,
+// linking the files written by GenMarkdownTree for the keywords around it
+func (f *FlagSet) GenMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", f.commandName(" "))
+	f.writeMarkdownBody(bw, "##", markdownFile)
+	if f.parent != nil {
+		fmt.Fprintf(bw, "\nSee also [%s](%s)\n", markdownCode(f.parent.commandName(" ")), markdownFile(f.parent))
+	}
+	return bw.Flush()
+}
+
+// GenMarkdownTree writes into dir one Markdown file for every keyword
+// path below this one, named as in prog_keyword_subkeyword.md
+func (f *FlagSet) GenMarkdownTree(dir string) error {
+	var err error
+	f.walkVisible(func(s *FlagSet) {
+		if err == nil {
+			err = writeDocFile(filepath.Join(dir, markdownFile(s)), s.GenMarkdown)
+		}
+	})
+	return err
+}
+
+// GenMarkdownSingle writes a single Markdown document describing the
+// whole tree below this keyword path
+func (f *FlagSet) GenMarkdownSingle(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", f.commandName(" "))
+	f.writeMarkdownBody(bw, "##", markdownAnchor)
+	f.walkVisible(func(s *FlagSet) {
+		if s != f {
+			fmt.Fprintf(bw, "\n## %s\n", s.commandName(" "))
+			s.writeMarkdownBody(bw, "###", markdownAnchor)
+		}
+	})
+	return bw.Flush()
+}
+
+func writeDocFile(path string, gen func(io.Writer) error) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gen(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,76 @@
+package gu_flag
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func docsTree() *FlagSet {
+	root := NewRoot("prog", "manage .hats")
+	root.Flags.Bool("verbose", false, "be verbose")
+	hat := root.NewFlagSet("hat", "set hat characteristics", "h")
+	hat.Flags.String("style", "plain", "hat style")
+	hat.NewFlagSet("color", "set hat color")
+	return root
+}
+
+func TestGenManHeader(t *testing.T) {
+	var out bytes.Buffer
+	header := ManHeader{Date: "Oct 2026", Source: `prog "1.0"`, Manual: `C:\Tools`}
+	if err := docsTree().GenMan(&out, header); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if want := `.TH "PROG" "1" "Oct 2026" "prog \(dq1.0\(dq" "C:\eTools"`; lines[0] != want {
+		t.Errorf("got %s, want %s", lines[0], want)
+	}
+	if !strings.Contains(out.String(), `.SH NAME`+"\n"+`prog \- manage .hats`) {
+		t.Errorf("bad NAME section:\n%s", out.String())
+	}
+}
+
+func TestGenMan(t *testing.T) {
+	var out bytes.Buffer
+	hat := docsTree().subSets["hat"]
+	if err := hat.GenMan(&out, ManHeader{Section: "8"}); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+	for _, s := range []string{
+		`.TH "PROG-HAT" "8"`,
+		`\fB\-style\fR`,
+		`\fB\-verbose\fR`,
+		"\\fBcolor\\fR\nset hat color",
+		`.SH "SEE ALSO"` + "\n" + `\fBprog\fR(8), \fBprog-hat-color\fR(8)`,
+	} {
+		if !strings.Contains(page, s) {
+			t.Errorf("%q not found in:\n%s", s, page)
+		}
+	}
+}
+
+func TestGenDocTrees(t *testing.T) {
+	dir := t.TempDir()
+	root := docsTree()
+	if err := root.GenManTree(dir, ManHeader{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.GenMarkdownTree(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"prog.1", "prog-hat.1", "prog-hat-color.1", "prog.md", "prog_hat.md", "prog_hat_color.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	var out bytes.Buffer
+	if err := root.GenMarkdownSingle(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "`-style string`") || !strings.Contains(out.String(), "prog hat color") {
+		t.Errorf("incomplete Markdown:\n%s", out.String())
+	}
+}
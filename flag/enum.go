@@ -0,0 +1,123 @@
+// Enum flags take a single value out of a list of allowed ones, matched
+// ignoring case if requested; they share the canonicalization and the
+// allowed values of constrained sets, but can be given only once
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ALREADY_GIVEN error = errors.New("value already given")
+)
+
+type EnumError struct {
+	Value   string
+	Allowed []string
+	Err     error
+}
+
+func (ee *EnumError) Error() string {
+	return fmt.Sprintf("%q: %v (allowed: %s)", ee.Value, ee.Err, strings.Join(ee.Allowed, ", "))
+}
+
+func (ee *EnumError) Unwrap() error {
+	return ee.Err
+}
+
+type enumArg struct {
+	*setArg
+	target   *string
+	initial  string
+	elements []string
+}
+
+// Set stores the allowed value matching v, as spelled in the allowed
+// list
+func (ea *enumArg) Set(v string) error {
+	if !ea.IsDefault() {
+		return &EnumError{Value: v, Allowed: ea.allowed, Err: ALREADY_GIVEN}
+	}
+	a, ok := ea.match(v)
+	if !ok {
+		return &EnumError{Value: v, Allowed: ea.allowed, Err: NOT_IN_SET}
+	}
+	ea.store(a)
+	ea.setDefault(false)
+	return nil
+}
+
+// match returns the allowed value matching v
+func (ea *enumArg) match(v string) (string, bool) {
+	c := ea.canonicalizer(v)
+	for _, a := range ea.allowed {
+		if ea.canonicalizer(a) == c {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+func (ea *enumArg) store(v string) {
+	ea.reset()
+	if len(v) > 0 {
+		ea.elements = append(ea.elements, v)
+		ea.values_present[ea.canonicalizer(v)] = true
+	}
+	*ea.target = v
+}
+
+func (ea *enumArg) String() string {
+	if ea == nil || ea.setArg == nil {
+		return ""
+	}
+	return *ea.target
+}
+
+func (ea *enumArg) Get() interface{} {
+	return *ea.target
+}
+
+func (ea *enumArg) restoreDefault() {
+	ea.store(ea.initial)
+	ea.setDefault(true)
+}
+
+func (ea *enumArg) clone(c *FlagSet, name string) RepeatableArg {
+	return ea.factory(c, name)
+}
+
+func (f *FlagSet) Enum(name string, value string, allowed []string, usage string, ignoreCase bool) *string {
+	p := new(string)
+	f.EnumVar(p, name, value, allowed, usage, ignoreCase)
+	return p
+}
+
+// EnumVar defines a flag taking one of the allowed values; value is its
+// default, and can be empty even if not allowed; EnumVar panics on a
+// default that is not
+func (f *FlagSet) EnumVar(p *string, name string, value string, allowed []string, usage string, ignoreCase bool) {
+	ea := &enumArg{target: p, initial: value}
+	defaults := []string{}
+	if len(value) > 0 {
+		defaults = append(defaults, value)
+	}
+	ea.setArg = newSetArg(&ea.elements, defaults, ignoreCase)
+	ea.allowed = allowed
+	if _, ok := ea.match(value); len(value) > 0 && !ok {
+		panic(fmt.Sprintf("bad default %q for -%s: not one of %s", value, name, strings.Join(allowed, ", ")))
+	}
+	ea.factory = func(c *FlagSet, name string) RepeatableArg {
+		c.EnumVar(new(string), name, value, allowed, usage, ignoreCase)
+		return c.Flags.Lookup(name).Value.(RepeatableArg)
+	}
+	*p = value
+	f.Flags.Var(ea, name, usage)
+}
+
+func isEnum(v interface{}) bool {
+	_, ok := v.(*enumArg)
+	return ok
+}
@@ -0,0 +1,92 @@
+package gu_flag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEnum(t *testing.T) {
+	cases := []struct {
+		args       string
+		ignoreCase bool
+		want       string
+	}{
+		{"", false, "json"},
+		{"-format=yaml", false, "yaml"},
+		{"-format=YAML", true, "yaml"},
+		{"-format=Text", true, "Text"},
+	}
+	for _, c := range cases {
+		root := NewRoot("prog", "")
+		format := root.Enum("format", "json", []string{"json", "yaml", "Text"}, "", c.ignoreCase)
+		if err := root.Parse(strings.Fields(c.args)); err != nil {
+			t.Errorf("%q: %v", c.args, err)
+		} else if *format != c.want || root.Value("format") != c.want {
+			t.Errorf("%q: got %s", c.args, *format)
+		}
+	}
+}
+
+func TestEnumErrors(t *testing.T) {
+	cases := []struct {
+		args        string
+		err         error
+		suggestions string
+	}{
+		{"-format=YAML", NOT_IN_SET, ""},
+		{"-format=jsonn", NOT_IN_SET, "json"},
+		{"-format=markdwn", NOT_IN_SET, "markdown"},
+		{"-format=xml", NOT_IN_SET, ""},
+		{"-format=json -format=yaml", ALREADY_GIVEN, ""},
+	}
+	for _, c := range cases {
+		root := NewRoot("prog", "")
+		root.Enum("format", "", []string{"json", "yaml", "markdown"}, "", false)
+		err := root.Parse(strings.Fields(c.args))
+		var pe *ParseError
+		var ee *EnumError
+		if !errors.As(err, &pe) || pe.Kind != BadFlagValue || !errors.As(err, &ee) || ee.Err != c.err {
+			t.Errorf("%q: got %v", c.args, err)
+			continue
+		}
+		if strings.Join(ee.Allowed, ",") != "json,yaml,markdown" || strings.Join(pe.Suggestions, ",") != c.suggestions {
+			t.Errorf("%q: allowed %q, suggestions %q", c.args, ee.Allowed, pe.Suggestions)
+		}
+	}
+}
+
+func TestEnumEmptyDefault(t *testing.T) {
+	root := NewRoot("prog", "")
+	format := root.Enum("format", "", []string{"json", "yaml"}, "", false)
+	if err := root.Parse([]string{"-format=json"}); err != nil || *format != "json" {
+		t.Fatalf("got %q, %v", *format, err)
+	}
+	root.Reset()
+	if err := root.Parse(nil); err != nil || *format != "" {
+		t.Errorf("default not restored: %q, %v", *format, err)
+	}
+}
+
+func TestEnumBadDefault(t *testing.T) {
+	cases := []struct {
+		value      string
+		ignoreCase bool
+		ok         bool
+	}{
+		{"xml", false, false},
+		{"YAML", false, false},
+		{"YAML", true, true},
+		{"", false, true},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if r := recover(); (r == nil) != c.ok {
+					t.Errorf("%q, ignoreCase %v: got %v", c.value, c.ignoreCase, r)
+				}
+			}()
+			NewRoot("prog", "").Enum("format", c.value, []string{"json", "yaml"}, "", c.ignoreCase)
+		}()
+	}
+}
@@ -0,0 +1,66 @@
+// Flags can be bound to environment variables named after the keyword
+// path: -index under "hat color" is read from [PREFIX_]HAT_COLOR_INDEX.
+// Values given on the command line always take precedence
+package gu_flag
+
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+var (
+	envReplacer = strings.NewReplacer("-", "_", ".", "_", " ", "_")
+)
+
+// BindEnv enables environment lookup for this FlagSet and all the
+// subgrammars below it; prefix, if not empty, is prepended to the
+// variable names
+func (f *FlagSet) BindEnv(prefix string) {
+	f.envBound = true
+	f.envPrefix = prefix
+}
+
+func (f *FlagSet) envBinding() (bool, string) {
+	for s := f; s != nil; s = s.parent {
+		if s.envBound {
+			return true, s.envPrefix
+		}
+	}
+	return false, ""
+}
+
+// EnvName returns the environment variable bound to the named flag
+func (f *FlagSet) EnvName(name string) string {
+	_, prefix := f.envBinding()
+	parts := append(f.Path(), name)
+	if len(prefix) > 0 {
+		parts = append([]string{prefix}, parts...)
+	}
+	return strings.ToUpper(envReplacer.Replace(strings.Join(parts, "_")))
+}
+
+func (f *FlagSet) applyEnvironment() error {
+	if bound, _ := f.envBinding(); !bound {
+		return nil
+	}
+	var err error
+	f.Flags.VisitAll(func(fl *flag.Flag) {
+		if _, isAlias := f.aliases[fl.Name]; err != nil || isAlias || f.isSet(fl.Name) {
+			return
+		}
+		name := f.EnvName(fl.Name)
+		if v, found := os.LookupEnv(name); found {
+			if e := f.setFromSource(name, fl.Name, OriginEnvironment, v); e != nil {
+				if pe, ok := e.(*ParseError); ok {
+					err = pe
+					return
+				}
+				pe := f.parseError(BadFlagValue, name, e)
+				pe.Flag, pe.Value = fl.Name, v
+				err = pe
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,81 @@
+package gu_flag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type envFlags struct {
+	dryRun bool
+	index  int
+	shade  []string
+}
+
+func envTree() (*FlagSet, *envFlags) {
+	e := &envFlags{}
+	root := NewRoot("prog", "")
+	root.Flags.BoolVar(&e.dryRun, "dry-run", false, "")
+	hat := root.NewFlagSet("hat", "")
+	color := hat.NewFlagSet("color", "")
+	color.Flags.IntVar(&e.index, "index", -1, "")
+	color.ListVar(&e.shade, "shade", nil, "")
+	return root, e
+}
+
+func TestEnvNames(t *testing.T) {
+	root, _ := envTree()
+	color := root.subSets["hat"].subSets["color"]
+	root.BindEnv("")
+	if n := color.EnvName("index"); n != "HAT_COLOR_INDEX" {
+		t.Errorf("got %s", n)
+	}
+	root.BindEnv("my-app")
+	if n := root.EnvName("dry-run"); n != "MY_APP_DRY_RUN" {
+		t.Errorf("got %s", n)
+	}
+}
+
+func TestEnvValues(t *testing.T) {
+	root, e := envTree()
+	root.BindEnv("APP")
+	t.Setenv("APP_DRY_RUN", "true")
+	t.Setenv("APP_HAT_COLOR_INDEX", "4")
+	t.Setenv("APP_HAT_COLOR_SHADE", "a,b")
+	if err := root.Parse([]string{"hat", "color"}); err != nil {
+		t.Fatal(err)
+	}
+	color := root.subSets["hat"].subSets["color"]
+	if !e.dryRun || e.index != 4 || strings.Join(e.shade, ",") != "a,b" {
+		t.Errorf("got %+v", *e)
+	}
+	if o := color.Origin("index"); o != OriginEnvironment {
+		t.Errorf("origin %v", o)
+	}
+	root, e = envTree()
+	root.BindEnv("APP")
+	if err := root.Parse([]string{"hat", "color", "-index=7"}); err != nil {
+		t.Fatal(err)
+	}
+	color = root.subSets["hat"].subSets["color"]
+	if e.index != 7 || color.Origin("index") != OriginCommandLine {
+		t.Errorf("command line not taking precedence: %v", e.index)
+	}
+}
+
+func TestEnvUnbound(t *testing.T) {
+	root, e := envTree()
+	t.Setenv("HAT_COLOR_INDEX", "4")
+	if err := root.Parse([]string{"hat", "color"}); err != nil {
+		t.Fatal(err)
+	}
+	if e.index != -1 {
+		t.Errorf("unbound flag read from the environment: %v", e.index)
+	}
+	root.BindEnv("")
+	t.Setenv("HAT_COLOR_INDEX", "four")
+	var pe *ParseError
+	if err := root.Parse([]string{"hat", "color"}); !errors.As(err, &pe) || pe.Kind != BadFlagValue || pe.Token != "HAT_COLOR_INDEX" {
+		t.Errorf("got %v", err)
+	}
+}
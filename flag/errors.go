@@ -0,0 +1,205 @@
+// Parse errors carry the keyword path where they happened, the offending
+// token and, for misspelled keywords and flags, the closest alternatives.
+// The error returned by the Set method of a flag value is kept in Err as
+// it is, so that Err == NOT_IN_SET still holds for sets
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ErrorKind int
+
+const (
+	UnknownKeyword ErrorKind = iota + 1
+	AmbiguousKeyword
+	MissingKeyword
+	UnexpectedPositional
+	MissingPositional
+	BadPositionalValue
+	UnknownFlag
+	BadFlagValue
+	ConstraintViolation
+	Removed
+)
+
+var (
+	MISSING_FLAG_VALUE error = errors.New("flag needs an argument")
+	BAD_FLAG_SYNTAX    error = errors.New("bad flag syntax")
+)
+
+type ParseError struct {
+	Kind        ErrorKind
+	Path        []string
+	Token       string
+	Flag        string
+	Value       string
+	Suggestions []string
+	Err         error
+	File        string
+	Line        int
+	argsLeft    int
+	reported    bool
+}
+
+func (k ErrorKind) String() string {
+	switch k {
+	case UnknownKeyword:
+		return "unknown keyword"
+	case AmbiguousKeyword:
+		return "ambiguous keyword"
+	case MissingKeyword:
+		return "missing keyword"
+	case UnexpectedPositional:
+		return "unexpected argument"
+	case MissingPositional:
+		return "missing argument"
+	case BadPositionalValue:
+		return "invalid argument"
+	case UnknownFlag:
+		return "unknown flag"
+	case BadFlagValue:
+		return "invalid flag value"
+	case ConstraintViolation:
+		return "constraint violation"
+	case Removed:
+		return "removed"
+	}
+	return fmt.Sprintf("ErrorKind(%d)", k)
+}
+
+// isGrammar tells the errors made using the keywords, the flags or the
+// positional args in the wrong way, rather than giving bad values
+func (pe *ParseError) isGrammar() bool {
+	switch pe.Kind {
+	case UnknownKeyword, AmbiguousKeyword, MissingKeyword, UnexpectedPositional, MissingPositional, UnknownFlag:
+		return true
+	case BadFlagValue:
+		return pe.Err == MISSING_FLAG_VALUE || pe.Err == BAD_FLAG_SYNTAX
+	}
+	return false
+}
+
+// Error prefixes the message with the position of the offending token
+// when it comes from a response file
+func (pe *ParseError) Error() string {
+	if len(pe.File) > 0 {
+		return fmt.Sprintf("%s:%d: %s", pe.File, pe.Line, pe.message())
+	}
+	return pe.message()
+}
+
+func (pe *ParseError) message() string {
+	switch pe.Kind {
+	case UnknownKeyword:
+		return "unknown keyword: " + pe.Token + didYouMean(pe.Suggestions, "")
+	case AmbiguousKeyword:
+		return fmt.Sprintf("ambiguous keyword: %s (could be: %s)", pe.Token, strings.Join(pe.Suggestions, ","))
+	case MissingKeyword:
+		return fmt.Sprintf("missing keyword (try one of: %s)", strings.Join(pe.Suggestions, ","))
+	case UnexpectedPositional:
+		return "unexpected argument: " + pe.Token
+	case MissingPositional:
+		return "missing argument " + pe.Token
+	case BadPositionalValue:
+		return fmt.Sprintf("invalid argument %s %q: %v", pe.Token, pe.Value, pe.Err)
+	case UnknownFlag:
+		return "flag provided but not defined: -" + pe.Flag + didYouMean(pe.Suggestions, "-")
+	case BadFlagValue:
+		if pe.Err == MISSING_FLAG_VALUE || pe.Err == BAD_FLAG_SYNTAX {
+			return fmt.Sprintf("%v: %s", pe.Err, pe.Token)
+		}
+		return fmt.Sprintf("invalid value %q for flag -%s: %v", pe.Value, pe.Flag, pe.Err) + didYouMean(pe.Suggestions, "")
+	case Removed:
+		if len(pe.Flag) > 0 {
+			return pe.Err.Error() + didYouMean(pe.Suggestions, "-")
+		}
+		return pe.Err.Error() + didYouMean(pe.Suggestions, "")
+	}
+	return pe.Err.Error()
+}
+
+func (pe *ParseError) Unwrap() error {
+	return pe.Err
+}
+
+func didYouMean(suggestions []string, prefix string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %s%s?)", prefix, strings.Join(suggestions, " or "+prefix))
+}
+
+func (f *FlagSet) parseError(kind ErrorKind, token string, err error) *ParseError {
+	return &ParseError{Kind: kind, Path: f.Path(), Token: token, Err: err}
+}
+
+// alreadyReported tells if err has been printed by flagError already
+func alreadyReported(err error) bool {
+	var pe *ParseError
+	return errors.As(err, &pe) && pe.reported
+}
+
+// markArgsLeft records, on parse errors not knowing it yet, how many
+// args were left starting from the offending one
+func markArgsLeft(err error, left int) {
+	if pe, ok := err.(*ParseError); ok && pe.argsLeft == 0 {
+		pe.argsLeft = left
+	}
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		diag := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			next := diag + cost
+			if row[j]+1 < next {
+				next = row[j] + 1
+			}
+			if row[j-1]+1 < next {
+				next = row[j-1] + 1
+			}
+			diag, row[j] = row[j], next
+		}
+	}
+	return row[len(rb)]
+}
+
+// suggestions returns the candidates closest to token, if close enough
+// to be a likely misspelling
+func suggestions(token string, candidates []string) []string {
+	limit := len(token) / 3
+	if limit > 3 {
+		limit = 3
+	} else if limit < 1 && len(token) > 1 {
+		limit = 1
+	}
+	best := limit + 1
+	r := []string{}
+	for _, c := range candidates {
+		d := editDistance(token, c)
+		switch {
+		case d < best:
+			best = d
+			r = []string{c}
+		case d == best && d <= limit:
+			r = append(r, c)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,124 @@
+package gu_flag
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func errorsTree() *FlagSet {
+	root := NewRoot("prog", "")
+	root.PrefixMatching = true
+	root.Flags.Bool("verbose", false, "")
+	hat := root.NewFlagSet("hat", "")
+	hat.ConstrainedSet("color", nil, []string{"red", "green", "blue"}, "", false)
+	hat.NewFlagSet("remove", "")
+	hat.NewFlagSet("rename", "")
+	return root
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		args        string
+		kind        ErrorKind
+		suggestions []string
+	}{
+		{"hat -colour=red", UnknownFlag, []string{"color"}},
+		{"hatt", UnknownKeyword, []string{"hat"}},
+		{"hat re", AmbiguousKeyword, []string{"remove", "rename"}},
+		{"hat", MissingKeyword, []string{"remove", "rename"}},
+		{"-verbose=maybe hat", BadFlagValue, nil},
+		{"hat -color=red,gren remove", BadFlagValue, []string{"green"}},
+		{"hat remove x", UnexpectedPositional, nil},
+	}
+	for _, c := range cases {
+		err := errorsTree().Parse(strings.Fields(c.args))
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Kind != c.kind {
+			t.Errorf("%q: got %v, want %v", c.args, err, c.kind)
+			continue
+		}
+		if strings.Join(pe.Suggestions, ",") != strings.Join(c.suggestions, ",") {
+			t.Errorf("%q: suggestions %q, want %q", c.args, pe.Suggestions, c.suggestions)
+		}
+	}
+}
+
+func TestSetErrorsUnwrapped(t *testing.T) {
+	root := errorsTree()
+	err := root.Parse([]string{"hat", "-color=red,pink", "remove"})
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Err != NOT_IN_SET || pe.Flag != "color" {
+		t.Errorf("got %#v", err)
+	}
+	color := root.subSets["hat"].Flags.Lookup("color")
+	if err := color.Value.Set("red,red"); err != DUPLICATE_VALUE {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestStandardContract(t *testing.T) {
+	root := errorsTree()
+	if err := root.Parse([]string{"-verbose", "hat", "remove"}); err != nil {
+		t.Fatal(err)
+	}
+	remove := root.subSets["hat"].subSets["remove"]
+	for _, s := range []*FlagSet{root, root.subSets["hat"], remove} {
+		if !s.Flags.Parsed() {
+			t.Errorf("%v: Flags not parsed", s.Path())
+		}
+	}
+	if args := root.Flags.Args(); strings.Join(args, " ") != "hat remove" {
+		t.Errorf("got args %q", args)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	root := errorsTree()
+	var out bytes.Buffer
+	root.SetOutput(&out)
+	root.Flags = flag.NewFlagSet("prog", flag.PanicOnError)
+	root.Flags.Bool("verbose", false, "")
+	usage := false
+	root.Flags.Usage = func() { usage = true }
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("no panic")
+			}
+		}()
+		root.Parse([]string{"-nosuch", "hat", "remove"})
+	}()
+	if !usage || !strings.Contains(out.String(), "-nosuch") {
+		t.Errorf("usage=%v output %q", usage, out.String())
+	}
+	out.Reset()
+	hat := root.subSets["hat"]
+	if err := root.Parse([]string{"hat", "-nosuch"}); err == nil || out.Len() != 0 {
+		t.Errorf("got %v, output %q", err, out.String())
+	}
+	if err := hat.Parse([]string{"-h"}); err != flag.ErrHelp || !strings.Contains(out.String(), "remove") {
+		t.Errorf("got %v, output %q", err, out.String())
+	}
+}
+
+func TestUsageFunction(t *testing.T) {
+	root := errorsTree()
+	var out bytes.Buffer
+	root.SetOutput(&out)
+	root.Flags.Usage = func() { fmt.Fprintln(&out, "Usage of prog") }
+	if err := root.Parse([]string{"-nosuch"}); !alreadyReported(err) {
+		t.Errorf("got %v", err)
+	}
+	if got := out.String(); got != "flag provided but not defined: -nosuch\nUsage of prog\n" {
+		t.Errorf("got output %q", got)
+	}
+	out.Reset()
+	if code := root.Execute(context.Background(), []string{"-nosuch"}); code != EXIT_USAGE || strings.Contains(out.String(), "Usage of prog") {
+		t.Errorf("got exit code %d, output %q", code, out.String())
+	}
+}
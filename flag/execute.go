@@ -0,0 +1,87 @@
+// Execute is the whole main of a program: it parses the command line,
+// runs the hooks and turns the outcome into an exit code, reporting
+// errors on the output of the root. The help of the keyword involved
+// follows only the errors in the grammar of the command, like unknown
+// flags and keywords or missing and unexpected args, and not the bad
+// values, from the command line or from the environment and the
+// configuration files
+package gu_flag
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+const (
+	EXIT_SUCCESS = 0
+	EXIT_FAILURE = 1
+	EXIT_USAGE   = 2
+)
+
+// ExitCode lets a hook choose the exit code of Execute; Err, if not nil,
+// is reported as the error
+type ExitCode struct {
+	Code int
+	Err  error
+}
+
+func (ec *ExitCode) Error() string {
+	if ec.Err == nil {
+		return fmt.Sprintf("exit status %d", ec.Code)
+	}
+	return ec.Err.Error()
+}
+
+func (ec *ExitCode) Unwrap() error {
+	return ec.Err
+}
+
+// Execute parses args and runs the selected keyword, returning 0 on
+// success or help requests, the code of an ExitCode error, 2 for errors
+// in the command line, the configuration or the response files, and 1
+// for any other error, panics of HookFunc included. Parse errors are
+// reported by Execute alone, without the usage of Flags and without
+// exiting, even when Flags would do that, as flag.CommandLine in MainSet
+//
+//	os.Exit(gu_flag.MainSet.Execute(context.Background(), os.Args[1:]))
+func (f *FlagSet) Execute(ctx context.Context, args []string) int {
+	root := f.Root()
+	executing := root.executing
+	root.executing = true
+	err := f.ParseContext(ctx, args)
+	root.executing = executing
+	if err == nil || err == flag.ErrHelp {
+		return EXIT_SUCCESS
+	}
+	w := f.Output()
+	var ec *ExitCode
+	if errors.As(err, &ec) {
+		if ec.Err != nil {
+			fmt.Fprintf(w, "%s: %v\n", f.ProgramName(), ec.Err)
+		}
+		return ec.Code
+	}
+	if alreadyReported(err) {
+		return EXIT_USAGE
+	}
+	fmt.Fprintf(w, "%s: %v\n", f.ProgramName(), err)
+	var pe *ParseError
+	var rfe *ResponseFileError
+	var ce *ConfigError
+	var panicked *PanicError
+	switch {
+	case errors.As(err, &pe):
+		if s, serr := f.Select(pe.Path...); serr == nil && pe.isGrammar() {
+			fmt.Fprintln(w)
+			s.WriteHelp(w)
+		}
+		return EXIT_USAGE
+	case errors.As(err, &rfe), errors.As(err, &ce):
+		return EXIT_USAGE
+	case errors.As(err, &panicked):
+		w.Write(panicked.Stack)
+	}
+	return EXIT_FAILURE
+}
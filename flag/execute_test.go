@@ -0,0 +1,90 @@
+package gu_flag
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func executeTree(out *bytes.Buffer) *FlagSet {
+	root := NewRoot("prog", "")
+	root.SetOutput(out)
+	root.BindEnv("PROG")
+	root.Flags.Int("level", 1, "")
+	run := root.NewFlagSet("run", "run the job")
+	run.Flags.String("mode", "", "")
+	run.HookFunc = func(self *FlagSet) error {
+		switch self.Value("mode") {
+		case "fail":
+			return errors.New("job failed")
+		case "exit":
+			return &ExitCode{Code: 7}
+		case "panic":
+			panic("boom")
+		}
+		return nil
+	}
+	return root
+}
+
+func TestExecute(t *testing.T) {
+	cases := []struct {
+		args string
+		code int
+		out  string
+		help bool
+	}{
+		{"run", EXIT_SUCCESS, "", false},
+		{"run -h", EXIT_SUCCESS, "run the job", true},
+		{"run -mode=fail", EXIT_FAILURE, "prog: job failed\n", false},
+		{"run -mode=exit", 7, "", false},
+		{"run -mode=panic", EXIT_FAILURE, "prog: panic: boom\n", false},
+		{"run -nosuch", EXIT_USAGE, "prog: flag provided but not defined: -nosuch\n", true},
+		{"nosuch", EXIT_USAGE, "prog: unknown keyword: nosuch\n", true},
+		{"", EXIT_USAGE, "prog: missing keyword (try one of: run)\n", true},
+		{"run -mode", EXIT_USAGE, "prog: flag needs an argument: -mode\n", true},
+		{"-level=x run", EXIT_USAGE, "prog: invalid value \"x\" for flag -level", false},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		code := executeTree(&out).Execute(context.Background(), strings.Fields(c.args))
+		if code != c.code || !strings.Contains(out.String(), c.out) {
+			t.Errorf("%q: got %d, output %q", c.args, code, out.String())
+		}
+		if help := strings.Contains(out.String(), "Usage:"); help != c.help {
+			t.Errorf("%q: help printed %v:\n%s", c.args, help, out.String())
+		}
+	}
+}
+
+func TestExecuteSourceErrors(t *testing.T) {
+	var out bytes.Buffer
+	t.Setenv("PROG_LEVEL", "high")
+	code := executeTree(&out).Execute(context.Background(), []string{"run"})
+	if code != EXIT_USAGE || strings.Count(out.String(), "\n") != 1 || !strings.Contains(out.String(), `"high"`) {
+		t.Errorf("got %d, output %q", code, out.String())
+	}
+}
+
+func TestExecuteExitingFlags(t *testing.T) {
+	var out bytes.Buffer
+	root := executeTree(&out)
+	root.Flags = flag.NewFlagSet("prog", flag.ExitOnError)
+	root.Flags.SetOutput(&out)
+	root.Flags.Usage = func() {
+		fmt.Fprintln(&out, "Usage of prog:")
+	}
+	root.Flags.Int("count", 0, "")
+	code := root.Execute(context.Background(), []string{"-count=abc", "run"})
+	if code != EXIT_USAGE || strings.Contains(out.String(), "Usage of prog:") || !strings.Contains(out.String(), `prog: invalid value "abc" for flag -count`) {
+		t.Errorf("got %d, output %q", code, out.String())
+	}
+	out.Reset()
+	if code := root.Execute(context.Background(), []string{"-nosuch", "run"}); code != EXIT_USAGE || !strings.Contains(out.String(), "Usage: prog") {
+		t.Errorf("got %d, output %q", code, out.String())
+	}
+}
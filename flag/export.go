@@ -0,0 +1,174 @@
+// The effective configuration of the last parse can be exported as a
+// JSON document nesting the keywords as the configuration files do: each
+// level of the selected keyword path holds its flags, as objects with
+// the value and its origin, and the object of the next keyword; the args
+// following the last keyword are kept under "--". Since flags are
+// described by objects, the document is not a configuration file that
+// LoadConfig can read; replaying it gives again all the values not
+// coming from defaults, as if given on the command line
+package gu_flag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/maxcalandrelli/goutil/encoding/json"
+)
+
+const (
+	EXPORT_VALUE_KEY  = "value"
+	EXPORT_ORIGIN_KEY = "origin"
+	EXPORT_ARGS_KEY   = "--"
+)
+
+var (
+	BAD_EXPORT error = errors.New("not an exported configuration")
+)
+
+// Selected returns the FlagSet reached by the last parse of the tree
+// this FlagSet belongs to, or nil
+func (f *FlagSet) Selected() *FlagSet {
+	return f.Root().selected
+}
+
+func exportValue(fl *flag.Flag) interface{} {
+	if ra, ok := fl.Value.(RepeatableArg); ok && !isEnum(fl.Value) {
+		r := []interface{}{}
+		for _, v := range ra.GetValues() {
+			r = append(r, v)
+		}
+		return r
+	}
+	g, ok := fl.Value.(flag.Getter)
+	if !ok {
+		return fl.Value.String()
+	}
+	switch v := g.Get().(type) {
+	case bool, string, int, int64, uint, uint64, float64:
+		return v
+	case time.Duration:
+		return v.String()
+	}
+	return fl.Value.String()
+}
+
+// exportLevel describes the flags defined at this level, leaving out
+// aliases and deprecated flags
+func (f *FlagSet) exportLevel() map[string]interface{} {
+	r := map[string]interface{}{}
+	f.Flags.VisitAll(func(fl *flag.Flag) {
+		if _, isAlias := f.aliases[fl.Name]; isAlias || f.deprecated[fl.Name] != nil {
+			return
+		}
+		r[fl.Name] = map[string]interface{}{
+			EXPORT_VALUE_KEY:  exportValue(fl),
+			EXPORT_ORIGIN_KEY: f.Origin(fl.Name).String(),
+		}
+	})
+	return r
+}
+
+// Export describes the effective values of the flags along the keyword
+// path selected by the last parse
+func (f *FlagSet) Export() gu_json.JSONData {
+	leaf := f.Selected()
+	if leaf == nil {
+		return gu_json.JSONData(f.Root().exportLevel())
+	}
+	var data, level map[string]interface{}
+	for _, s := range leaf.lineage() {
+		next := s.exportLevel()
+		if level == nil {
+			data = next
+		} else {
+			level[s.subCommand] = next
+		}
+		level = next
+	}
+	args := []interface{}{}
+	for _, a := range leaf.args {
+		args = append(args, a)
+	}
+	level[EXPORT_ARGS_KEY] = args
+	return gu_json.JSONData(data)
+}
+
+func replayString(v interface{}) (string, bool) {
+	switch v.(type) {
+	case int, int64, uint, uint64:
+		return fmt.Sprint(v), true
+	}
+	return configString(v)
+}
+
+// ReplayArgs rebuilds from an exported document the args giving the same
+// values, leaving out the ones having their default
+func (f *FlagSet) ReplayArgs(data gu_json.JSONData) ([]string, error) {
+	r := []string{}
+	s := f.Root()
+	level := map[string]interface{}(data)
+	for level != nil {
+		var next map[string]interface{}
+		var keyword string
+		var nextSet *FlagSet
+		for _, k := range sortedKeys(level) {
+			v := level[k]
+			if sub, isKeyword := s.subSets[k]; isKeyword {
+				if next != nil {
+					return nil, &ConfigError{Path: s.Path(), Key: k, Err: BAD_EXPORT}
+				}
+				next, _ = v.(map[string]interface{})
+				keyword, nextSet = k, sub
+				continue
+			}
+			if k == EXPORT_ARGS_KEY {
+				continue
+			}
+			entry, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, &ConfigError{Path: s.Path(), Key: k, Err: BAD_EXPORT}
+			}
+			if entry[EXPORT_ORIGIN_KEY] == OriginDefault.String() {
+				continue
+			}
+			values := []interface{}{entry[EXPORT_VALUE_KEY]}
+			if list, isList := entry[EXPORT_VALUE_KEY].([]interface{}); isList {
+				values = list
+			}
+			for _, v := range values {
+				value, ok := replayString(v)
+				if !ok {
+					return nil, &ConfigError{Path: s.Path(), Key: k, Err: BAD_CONFIG_VALUE}
+				}
+				r = append(r, "-"+k+"="+value)
+			}
+		}
+		if next == nil {
+			args, _ := level[EXPORT_ARGS_KEY].([]interface{})
+			if len(args) > 0 {
+				r = append(r, "--")
+			}
+			for _, a := range args {
+				value, _ := replayString(a)
+				r = append(r, value)
+			}
+			break
+		}
+		r = append(r, keyword)
+		s, level = nextSet, next
+	}
+	return r, nil
+}
+
+// Replay parses again the invocation described by an exported document,
+// starting from the defaults
+func (f *FlagSet) Replay(data gu_json.JSONData) error {
+	args, err := f.ReplayArgs(data)
+	if err != nil {
+		return err
+	}
+	f.Root().Reset()
+	return f.Root().Parse(args)
+}
@@ -0,0 +1,78 @@
+package gu_flag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maxcalandrelli/goutil/encoding/json"
+)
+
+func exportTree() *FlagSet {
+	root := NewRoot("prog", "")
+	root.Flags.Bool("verbose", false, "")
+	root.Flags.Int("level", 1, "")
+	hat := root.NewFlagSet("hat", "")
+	hat.Flags.String("style", "plain", "")
+	hat.List("shade", []string{"dark"}, "")
+	hat.NonKeywordArgs = true
+	root.NewFlagSet("shoes", "")
+	return root
+}
+
+func TestExport(t *testing.T) {
+	root := exportTree()
+	root.BindEnv("")
+	t.Setenv("LEVEL", "3")
+	if err := root.Parse(strings.Fields("-verbose hat -shade=a -shade=b x y")); err != nil {
+		t.Fatal(err)
+	}
+	data := root.Export()
+	want := gu_json.JSONData{
+		"verbose": map[string]interface{}{"value": true, "origin": "command line"},
+		"level":   map[string]interface{}{"value": 3, "origin": "environment"},
+		"hat": map[string]interface{}{
+			"style": map[string]interface{}{"value": "plain", "origin": "default"},
+			"shade": map[string]interface{}{"value": []interface{}{"a", "b"}, "origin": "command line"},
+			"--":    []interface{}{"x", "y"},
+		},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got %v\nwant %v", data, want)
+	}
+	args, err := root.ReplayArgs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(args, " "); got != "-level=3 -verbose=true hat -shade=a -shade=b -- x y" {
+		t.Errorf("got replay args %q", got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	root := exportTree()
+	if err := root.Parse(strings.Fields("-level=2 hat -style=x z")); err != nil {
+		t.Fatal(err)
+	}
+	data := root.Export()
+	other := exportTree()
+	if err := other.Parse(strings.Fields("-verbose hat -shade=q")); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Replay(data); err != nil {
+		t.Fatal(err)
+	}
+	hat := other.subSets["hat"]
+	if other.Value("level") != 2 || hat.Value("style") != "x" || strings.Join(hat.Args(), " ") != "z" {
+		t.Errorf("got %v %v %v", other.Value("level"), hat.Value("style"), hat.Args())
+	}
+	if o := hat.Origin("style"); o != OriginCommandLine {
+		t.Errorf("replayed origin %v", o)
+	}
+	if other.Value("verbose") != false || strings.Join(hat.Value("shade").([]string), ",") != "dark" {
+		t.Errorf("values of the previous parse kept: %v %v", other.Value("verbose"), hat.Value("shade"))
+	}
+	if _, err := other.ReplayArgs(gu_json.JSONData{"level": 2.0}); err == nil {
+		t.Errorf("bad document accepted")
+	}
+}
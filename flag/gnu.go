@@ -0,0 +1,144 @@
+// GNU style parsing is enabled per FlagSet, and applies to the
+// subgrammars below it; it accepts:
+//   - bundled single letter boolean flags, as in -abc
+//   - --name value and --name=value
+//   - -- as terminator of the flags
+//   - flags interleaved with positional args, when NonKeywordArgs is set
+package gu_flag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Alias registers alias as another name of an already defined flag;
+// single letter aliases can be bundled in GNU style parsing
+func (f *FlagSet) Alias(alias, name string) error {
+	fl := f.Flags.Lookup(name)
+	if fl == nil {
+		return errors.New(fmt.Sprintf("no such flag -%s", name))
+	}
+	if f.Flags.Lookup(alias) != nil {
+		return errors.New(fmt.Sprintf("flag redefined: %s", alias))
+	}
+	f.Flags.Var(fl.Value, alias, fl.Usage)
+	if f.aliases == nil {
+		f.aliases = map[string]string{}
+	}
+	f.aliases[alias] = fl.Name
+	return nil
+}
+
+func (f *FlagSet) canonicalFlag(name string) string {
+	if n, ok := f.aliases[name]; ok {
+		return n
+	}
+	return name
+}
+
+func (f *FlagSet) flagAliases(name string) []string {
+	r := []string{}
+	for a, n := range f.aliases {
+		if n == name {
+			r = append(r, a)
+		}
+	}
+	return r
+}
+
+func (f *FlagSet) gnuStyle() bool {
+	for s := f; s != nil; s = s.parent {
+		if s.GNUStyle {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGNU sets the flags found in args, returning the positional args
+// for leaf subgrammars or the args starting from the keyword otherwise
+func (f *FlagSet) parseGNU(args []string) (rest []string, err error) {
+	positionals := []string{}
+	interleaved := len(f.subSets) == 0 && f.NonKeywordArgs
+	left := 0
+	defer func() { markArgsLeft(err, left) }()
+	for len(args) > 0 {
+		left = len(args)
+		a := args[0]
+		args = args[1:]
+		switch {
+		case a == "--":
+			return append(positionals, args...), nil
+		case len(a) < 2 || a[0] != '-':
+			if !interleaved {
+				return append(positionals, append([]string{a}, args...)...), nil
+			}
+			positionals = append(positionals, a)
+			continue
+		}
+		name := strings.TrimPrefix(a[1:], "-")
+		value := ""
+		hasValue := false
+		op := byte(0)
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value, hasValue = name[:i], name[i+1:], true
+			name, op = f.setOperator(name)
+		}
+		fl, err := f.lookupFlag(name)
+		switch {
+		case err == nil:
+		case err != flag.ErrHelp && !hasValue && len(name) > 1 && !strings.HasPrefix(a, "--"):
+			if err := f.setBundle(name, &args); err != nil {
+				return nil, err
+			}
+			continue
+		default:
+			return nil, err
+		}
+		if !hasValue {
+			if isBoolFlag(fl) {
+				value = "true"
+			} else if len(args) == 0 {
+				return nil, f.missingValue(a, name)
+			} else {
+				value, args = args[0], args[1:]
+			}
+		}
+		if err := f.setFlag(a, name, value, op); err != nil {
+			return nil, err
+		}
+	}
+	return positionals, nil
+}
+
+// setBundle handles -abc as -a -b -c, where only the last letter can
+// be a flag taking a value, either attached (-ovalue) or as next arg
+func (f *FlagSet) setBundle(bundle string, args *[]string) error {
+	for i, c := range bundle {
+		name := string(c)
+		fl, err := f.lookupFlag(name)
+		if err != nil {
+			if i == 0 && len(bundle) > 1 && err != flag.ErrHelp {
+				return f.unknownFlag("-"+bundle, bundle)
+			}
+			return err
+		}
+		if isBoolFlag(fl) {
+			if err := f.setFlag("-"+bundle, name, "true", 0); err != nil {
+				return err
+			}
+			continue
+		}
+		value := bundle[i+len(name):]
+		if len(value) == 0 {
+			if len(*args) == 0 {
+				return f.missingValue("-"+name, name)
+			}
+			value, *args = (*args)[0], (*args)[1:]
+		}
+		return f.setFlag("-"+bundle, name, value, 0)
+	}
+	return nil
+}
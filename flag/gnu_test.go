@@ -0,0 +1,92 @@
+package gu_flag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type gnuOptions struct {
+	all, long, human bool
+	output           string
+	width            int
+}
+
+func gnuTree() (*FlagSet, *gnuOptions) {
+	o := &gnuOptions{}
+	root := NewRoot("prog", "")
+	root.GNUStyle = true
+	root.Flags.BoolVar(&o.all, "all", false, "")
+	root.Flags.BoolVar(&o.long, "long", false, "")
+	root.Flags.BoolVar(&o.human, "human", false, "")
+	root.Flags.StringVar(&o.output, "output", "", "")
+	root.Flags.IntVar(&o.width, "width", 0, "")
+	for alias, name := range map[string]string{"a": "all", "l": "long", "H": "human", "o": "output", "w": "width"} {
+		root.Alias(alias, name)
+	}
+	ls := root.NewFlagSet("ls", "")
+	ls.NonKeywordArgs = true
+	return root, o
+}
+
+func TestGNUParsing(t *testing.T) {
+	cases := []struct {
+		args string
+		want gnuOptions
+		rest string
+	}{
+		{"-al ls", gnuOptions{all: true, long: true}, ""},
+		{"-lHo out ls", gnuOptions{long: true, human: true, output: "out"}, ""},
+		{"-ofile ls", gnuOptions{output: "file"}, ""},
+		{"--output out --width=80 ls", gnuOptions{output: "out", width: 80}, ""},
+		{"-w 3 ls x y", gnuOptions{width: 3}, "x y"},
+		{"ls -- -a", gnuOptions{}, "-a"},
+	}
+	for _, c := range cases {
+		root, o := gnuTree()
+		if err := root.Parse(strings.Fields(c.args)); err != nil {
+			t.Errorf("%q: %v", c.args, err)
+			continue
+		}
+		if *o != c.want || strings.Join(root.subSets["ls"].Args(), " ") != c.rest {
+			t.Errorf("%q: got %+v, args %q", c.args, *o, root.subSets["ls"].Args())
+		}
+	}
+}
+
+func TestGNUInterleaved(t *testing.T) {
+	root, _ := gnuTree()
+	ls := root.subSets["ls"]
+	sort := ls.Flags.Bool("sort", false, "")
+	ls.Alias("s", "sort")
+	if err := root.Parse(strings.Fields("ls x -s y -- -z")); err != nil {
+		t.Fatal(err)
+	}
+	if !*sort || strings.Join(ls.Args(), " ") != "x y -z" {
+		t.Errorf("got sort=%v args %q", *sort, ls.Args())
+	}
+}
+
+func TestGNUErrors(t *testing.T) {
+	cases := map[string]ErrorKind{
+		"-ax ls":    UnknownFlag,
+		"-xa ls":    UnknownFlag,
+		"--nosuch":  UnknownFlag,
+		"-a -o":     BadFlagValue,
+		"--width=x": BadFlagValue,
+	}
+	for args, kind := range cases {
+		root, _ := gnuTree()
+		var pe *ParseError
+		if err := root.Parse(strings.Fields(args)); !errors.As(err, &pe) || pe.Kind != kind {
+			t.Errorf("%q: got %v, want %v", args, err, kind)
+		}
+	}
+	root, _ := gnuTree()
+	if err := root.Alias("a", "long"); err == nil {
+		t.Errorf("alias redefined")
+	}
+	if err := root.Alias("z", "nosuch"); err == nil {
+		t.Errorf("alias of an undefined flag")
+	}
+}
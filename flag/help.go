@@ -0,0 +1,201 @@
+// Help for a subgrammar is requested either with -h/-help after its
+// keyword path or with the help keyword followed by the path, as in
+// "prog hat color -h" and "prog help hat color"
+package gu_flag
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	HELP_KEYWORD = "help"
+)
+
+func (f *FlagSet) Root() *FlagSet {
+	r := f
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r
+}
+
+// Path returns the keywords leading from the root to this subgrammar
+func (f *FlagSet) Path() []string {
+	if f.parent == nil {
+		return []string{}
+	}
+	return append(f.parent.Path(), f.subCommand)
+}
+
+func (f *FlagSet) ProgramName() string {
+	r := f.Root()
+	if len(r.subCommand) > 0 {
+		return r.subCommand
+	}
+	if len(r.Flags.Name()) > 0 {
+		return filepath.Base(r.Flags.Name())
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// Select returns the subgrammar reached following the given keywords
+func (f *FlagSet) Select(path ...string) (*FlagSet, error) {
+	s := f
+	for _, k := range path {
+		sub, err := s.lookupKeyword(k)
+		if err != nil {
+			return nil, err
+		}
+		s = sub
+	}
+	return s, nil
+}
+
+func (f *FlagSet) help(path []string) error {
+	s, err := f.Select(path...)
+	if err != nil {
+		return err
+	}
+	s.PrintHelp()
+	return flag.ErrHelp
+}
+
+func isBoolFlag(fl *flag.Flag) bool {
+	b, ok := fl.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (f *FlagSet) UsageLine() string {
+	r := strings.Join(append([]string{f.ProgramName()}, f.Path()...), " ")
+	if hasFlags(f.Flags) {
+		r += " [flags]"
+	}
+	switch {
+	case len(f.subSets) > 0:
+		r += " <keyword> ..."
+	case len(f.positionals) > 0:
+		r += " " + f.positionalsUsage()
+	case f.NonKeywordArgs:
+		r += " [args...]"
+	}
+	return r
+}
+
+func (f *FlagSet) PrintHelp() {
+	f.WriteHelp(f.Output())
+}
+
+// WriteHelp renders the usage of this subgrammar: its own flags and
+// keywords first, then the flags belonging to the keywords above it
+func (f *FlagSet) WriteHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s\n", f.UsageLine())
+	if len(f.usage) > 0 {
+		fmt.Fprintf(w, "\n%s\n", f.usage)
+	}
+	if kw := f.visibleKeywords(); len(kw) > 0 {
+		fmt.Fprintf(w, "\nKeywords:\n")
+		tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+		for _, k := range kw {
+			sub := f.subSets[k]
+			if len(sub.keywordAliases) > 0 {
+				k += " (" + strings.Join(sub.keywordAliases, ", ") + ")"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", k, sub.usage)
+		}
+		tw.Flush()
+	}
+	if len(f.positionals) > 0 {
+		fmt.Fprintf(w, "\nArguments:\n")
+		tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+		for _, pa := range f.positionals {
+			fmt.Fprintf(tw, "  %s\t%s\n", pa.String(), pa.usage)
+		}
+		tw.Flush()
+	}
+	f.writeFlagSection(w, "Flags", func(fl *flag.Flag) bool { return !f.isInherited(fl) })
+	f.writeFlagSection(w, "Persistent flags", f.isInherited)
+	if groups := f.constraintsDoc(); len(groups) > 0 {
+		fmt.Fprintf(w, "\nConstraints:\n  %s\n", strings.Join(groups, "\n  "))
+	}
+	for p := f.parent; p != nil; p = p.parent {
+		title := "Global flags"
+		if p.parent != nil {
+			title = fmt.Sprintf("Flags inherited from '%s'", strings.Join(p.Path(), " "))
+		}
+		p.writeFlagSection(w, title, func(fl *flag.Flag) bool {
+			found, _ := f.lookup(fl.Name)
+			return found == nil
+		})
+	}
+	if len(f.subSets) > 0 {
+		fmt.Fprintf(w, "\nUse \"%s <keyword> -h\" for more information about a keyword.\n", strings.Join(append([]string{f.ProgramName()}, f.Path()...), " "))
+	}
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	r := false
+	fs.VisitAll(func(*flag.Flag) { r = true })
+	return r
+}
+
+// writeFlags mimics flag.PrintDefaults, writing to w; aliases are
+// listed together with the flag they stand for
+func (f *FlagSet) writeFlags(w io.Writer, include func(*flag.Flag) bool) {
+	f.visitAll(func(fl *flag.Flag) {
+		if f.listed(fl) && include(fl) {
+			o := f.owner(fl.Name)
+			writeFlag(w, fl, o.flagAliases(fl.Name), o.isRequired(fl.Name))
+		}
+	})
+}
+
+func (f *FlagSet) writeFlagSection(w io.Writer, title string, include func(*flag.Flag) bool) {
+	found := false
+	f.visitAll(func(fl *flag.Flag) { found = found || (f.listed(fl) && include(fl)) })
+	if found {
+		fmt.Fprintf(w, "\n%s:\n", title)
+		f.writeFlags(w, include)
+	}
+}
+
+func writeFlag(w io.Writer, fl *flag.Flag, aliases []string, required bool) {
+	name, usage := flag.UnquoteUsage(fl)
+	sort.Strings(aliases)
+	line := " "
+	for _, a := range aliases {
+		line += " -" + a + ","
+	}
+	line += " -" + fl.Name
+	if len(name) > 0 {
+		line += " " + name
+	}
+	line += "\n    \t" + strings.ReplaceAll(usage, "\n", "\n    \t")
+	if values := allowedValues(fl); len(values) > 0 {
+		line += " (one of: " + strings.Join(values, ", ") + ")"
+	}
+	if required {
+		line += " (required)"
+	} else if !isZeroDefault(fl.DefValue) {
+		if name == "string" {
+			line += fmt.Sprintf(" (default %q)", fl.DefValue)
+		} else {
+			line += fmt.Sprintf(" (default %v)", fl.DefValue)
+		}
+	}
+	fmt.Fprintln(w, line)
+}
+
+func isZeroDefault(v string) bool {
+	switch v {
+	case "", "0", "false", "[]", "0s":
+		return true
+	}
+	return false
+}
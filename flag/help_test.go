@@ -0,0 +1,74 @@
+package gu_flag
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func helpTree() *FlagSet {
+	root := NewRoot("prog", "manage your wardrobe")
+	root.Flags.Bool("verbose", false, "be verbose")
+	hat := root.NewFlagSet("hat", "set hat characteristics", "cap")
+	hat.Flags.String("style", "plain", "hat `name` of the style")
+	hat.ConstrainedSet("heels", nil, []string{"low", "high"}, "heel height", false)
+	hat.Flags.Int("index", 0, "color index")
+	hat.Require("index")
+	color := hat.NewFlagSet("color", "set hat color")
+	color.Flags.String("shade", "", "color shade")
+	root.NewFlagSet("shoes", "set shoes size")
+	return root
+}
+
+func TestHelp(t *testing.T) {
+	var out bytes.Buffer
+	helpTree().subSets["hat"].WriteHelp(&out)
+	help := out.String()
+	for _, s := range []string{
+		"Usage: prog hat [flags] <keyword> ...\n\nset hat characteristics\n",
+		"Keywords:\n  color   set hat color\n",
+		"  -style name\n    \that name of the style (default plain)\n",
+		"heel height (one of: low, high)",
+		"  -index int\n    \tcolor index (required)\n",
+		"Global flags:\n  -verbose\n    \tbe verbose\n",
+		`Use "prog hat <keyword> -h" for more information about a keyword.`,
+	} {
+		if !strings.Contains(help, s) {
+			t.Errorf("%q not in help:\n%s", s, help)
+		}
+	}
+	out.Reset()
+	helpTree().WriteHelp(&out)
+	if !strings.Contains(out.String(), "  hat (cap)   set hat characteristics\n") {
+		t.Errorf("keyword aliases not listed:\n%s", out.String())
+	}
+}
+
+func TestHelpRequests(t *testing.T) {
+	cases := map[string]string{
+		"help":                  "Usage: prog [flags] <keyword> ...",
+		"help hat color":        "Usage: prog hat color [flags]",
+		"hat help color":        "Usage: prog hat color [flags]",
+		"hat -index=1 -h":       "Usage: prog hat [flags] <keyword> ...",
+		"shoes -help":           "Usage: prog shoes\n",
+		"hat -index=1 color -h": "Flags inherited from 'hat':",
+	}
+	for args, want := range cases {
+		root := helpTree()
+		var out bytes.Buffer
+		root.SetOutput(&out)
+		if err := root.Parse(strings.Fields(args)); err != flag.ErrHelp {
+			t.Errorf("%q: got %v", args, err)
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%q: %q not in help:\n%s", args, want, out.String())
+		}
+	}
+	var pe *ParseError
+	if _, err := helpTree().Select("hat", "nosuch"); err == nil {
+		t.Errorf("selected an undefined keyword")
+	} else if pe, _ = err.(*ParseError); pe == nil || pe.Kind != UnknownKeyword {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Around the HookFunc of the selected keyword, the PreRun hooks of the
+// keywords along its path are run from the root down, and their PostRun
+// hooks from the leaf up; a context.Context, that PreRun hooks can
+// enrich, is passed down the chain. A panicking HookFunc fails with a
+// PanicError, seen by the PostRun hooks like any other error
+package gu_flag
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", pe.Value)
+}
+
+// Context returns the context that reached this FlagSet in the last
+// parse, as enriched by the PreRun hooks above and at this level
+func (f *FlagSet) Context() context.Context {
+	for s := f; s != nil; s = s.parent {
+		if s.ctx != nil {
+			return s.ctx
+		}
+	}
+	return context.Background()
+}
+
+// run executes the hook chain for the selected leaf: PostRun hooks are
+// run, with the error so far, for every level whose PreRun succeeded,
+// even when a PreRun below or the HookFunc fails
+func (f *FlagSet) run(ctx context.Context) error {
+	lineage := f.lineage()
+	for _, s := range lineage {
+		s.ctx = nil
+	}
+	var err error
+	entered := 0
+	for _, s := range lineage {
+		if s.PreRun != nil {
+			var next context.Context
+			if next, err = s.PreRun(ctx, s); err != nil {
+				break
+			}
+			if next != nil {
+				ctx = next
+			}
+		}
+		s.ctx = ctx
+		entered++
+	}
+	if err == nil && f.HookFunc != nil {
+		err = f.callHook()
+	}
+	for i := entered - 1; i >= 0; i-- {
+		if s := lineage[i]; s.PostRun != nil {
+			err = s.PostRun(s.ctx, s, err)
+		}
+	}
+	return err
+}
+
+func (f *FlagSet) callHook() (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Value: p, Stack: debug.Stack()}
+		}
+	}()
+	return f.HookFunc(f)
+}
@@ -0,0 +1,105 @@
+package gu_flag
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type hookKey string
+
+// hooksTree records the hooks run, in order, in trace
+func hooksTree(trace *[]string) *FlagSet {
+	root := NewRoot("prog", "")
+	remote := root.NewFlagSet("remote", "")
+	add := remote.NewFlagSet("add", "")
+	for _, s := range []*FlagSet{root, remote, add} {
+		name := s.Name()
+		s.PreRun = func(ctx context.Context, self *FlagSet) (context.Context, error) {
+			*trace = append(*trace, "pre "+name)
+			return context.WithValue(ctx, hookKey(name), true), nil
+		}
+		s.PostRun = func(ctx context.Context, self *FlagSet, err error) error {
+			*trace = append(*trace, "post "+name)
+			return err
+		}
+	}
+	add.HookFunc = func(self *FlagSet) error {
+		*trace = append(*trace, "run")
+		return nil
+	}
+	return root
+}
+
+func TestHooksOrder(t *testing.T) {
+	trace := []string{}
+	root := hooksTree(&trace)
+	if err := root.Parse([]string{"remote", "add"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "pre prog,pre remote,pre add,run,post add,post remote,post prog"
+	if got := strings.Join(trace, ","); got != want {
+		t.Errorf("got %s", got)
+	}
+	ctx := root.subSets["remote"].subSets["add"].Context()
+	for _, k := range []string{"prog", "remote", "add"} {
+		if ctx.Value(hookKey(k)) != true {
+			t.Errorf("context not enriched by %s", k)
+		}
+	}
+	if root.Context().Value(hookKey("remote")) != nil {
+		t.Errorf("root context enriched below it")
+	}
+}
+
+func TestHooksErrors(t *testing.T) {
+	trace := []string{}
+	root := hooksTree(&trace)
+	remote := root.subSets["remote"]
+	failed := errors.New("failed")
+	remote.PreRun = func(ctx context.Context, self *FlagSet) (context.Context, error) {
+		return nil, failed
+	}
+	if err := root.Parse([]string{"remote", "add"}); err != failed {
+		t.Errorf("got %v", err)
+	}
+	if got := strings.Join(trace, ","); got != "pre prog,post prog" {
+		t.Errorf("got %s", got)
+	}
+	trace = trace[:0]
+	root = hooksTree(&trace)
+	var seen error
+	root.PostRun = func(ctx context.Context, self *FlagSet, err error) error {
+		seen = err
+		return nil
+	}
+	root.subSets["remote"].subSets["add"].HookFunc = func(*FlagSet) error { return failed }
+	if err := root.Parse([]string{"remote", "add"}); err != nil {
+		t.Errorf("error not cleared by PostRun: %v", err)
+	}
+	if seen != failed {
+		t.Errorf("PostRun got %v", seen)
+	}
+	root.subSets["remote"].subSets["add"].HookFunc = func(*FlagSet) error { panic("boom") }
+	if err := root.Parse([]string{"remote", "add"}); err != nil {
+		t.Errorf("error not cleared by PostRun: %v", err)
+	}
+	var pe *PanicError
+	if !errors.As(seen, &pe) || pe.Value != "boom" || len(pe.Stack) == 0 {
+		t.Errorf("PostRun got %v", seen)
+	}
+}
+
+func TestHooksContext(t *testing.T) {
+	root := NewRoot("prog", "")
+	var got interface{}
+	root.HookFunc = func(self *FlagSet) error {
+		got = self.Context().Value(hookKey("caller"))
+		return nil
+	}
+	ctx := context.WithValue(context.Background(), hookKey("caller"), "test")
+	if err := root.ParseContext(ctx, nil); err != nil || got != "test" {
+		t.Errorf("got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Keywords can be given through their aliases and, when prefix matching
+// is enabled, through any prefix selecting a single keyword. Names and
+// aliases must be unique among the keywords of a FlagSet: NewFlagSet
+// panics on a clash
+package gu_flag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func (f *FlagSet) Aliases() []string {
+	return f.keywordAliases
+}
+
+// keywordOwner returns the keyword named or aliased token, if any
+func (f *FlagSet) keywordOwner(token string) (string, bool) {
+	if _, ok := f.subSets[token]; ok {
+		return token, true
+	}
+	for name, s := range f.subSets {
+		for _, a := range s.keywordAliases {
+			if a == token {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// checkKeywordClash panics if the name or the aliases of a new keyword
+// are already taken by a keyword, or repeated
+func (f *FlagSet) checkKeywordClash(name string, aliases []string) {
+	for i, k := range append([]string{name}, aliases...) {
+		if owner, taken := f.keywordOwner(k); taken {
+			panic(fmt.Sprintf("keyword %s redefined: already used by keyword %s", k, owner))
+		}
+		for _, prev := range append([]string{name}, aliases...)[:i] {
+			if prev == k {
+				panic(fmt.Sprintf("keyword %s redefined: repeated in the aliases of %s", k, name))
+			}
+		}
+	}
+}
+
+func (f *FlagSet) prefixMatching() bool {
+	for s := f; s != nil; s = s.parent {
+		if s.PrefixMatching {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupKeyword finds the subgrammar selected by token, trying names
+// first, then aliases and finally prefixes of both; plugins are looked
+// for only when no keyword defined has the name or alias token
+func (f *FlagSet) lookupKeyword(token string) (*FlagSet, error) {
+	name, ok := f.keywordOwner(token)
+	if !ok {
+		f.loadPlugins()
+		name, ok = f.keywordOwner(token)
+	}
+	if ok {
+		return f.subSets[name], nil
+	}
+	if f.prefixMatching() && len(token) > 0 {
+		matches := map[*FlagSet]bool{}
+		candidates := []string{}
+		for name, s := range f.subSets {
+			if s.isHiddenKeyword() {
+				continue
+			}
+			for _, k := range append([]string{name}, s.keywordAliases...) {
+				if strings.HasPrefix(k, token) {
+					matches[s] = true
+					candidates = append(candidates, k)
+				}
+			}
+		}
+		if len(matches) == 1 {
+			for s, _ := range matches {
+				return s, nil
+			}
+		}
+		if len(matches) > 1 {
+			sort.Strings(candidates)
+			pe := f.parseError(AmbiguousKeyword, token, nil)
+			pe.Suggestions = candidates
+			return nil, pe
+		}
+	}
+	names := []string{}
+	for name, s := range f.subSets {
+		if !s.isHiddenKeyword() {
+			names = append(append(names, name), s.keywordAliases...)
+		}
+	}
+	pe := f.parseError(UnknownKeyword, token, nil)
+	pe.Suggestions = suggestions(token, names)
+	return nil, pe
+}
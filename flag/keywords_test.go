@@ -0,0 +1,120 @@
+package gu_flag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func keywordsTree(prefix bool) *FlagSet {
+	root := NewRoot("prog", "")
+	root.PrefixMatching = prefix
+	remote := root.NewFlagSet("remote", "", "rm")
+	remote.NewFlagSet("add", "")
+	remote.NewFlagSet("rename", "", "mv")
+	remote.NewFlagSet("remove", "")
+	remote.NewFlagSet("rewind", "").Hidden = true
+	root.NewFlagSet("status", "", "st")
+	return root
+}
+
+func TestKeywordAliases(t *testing.T) {
+	cases := map[string]string{
+		"remote add": "remote add",
+		"rm add":     "remote add",
+		"rm mv":      "remote rename",
+		"st":         "status",
+		"stat":       "",
+	}
+	for args, want := range cases {
+		s, err := keywordsTree(false).Select(strings.Fields(args)...)
+		if want == "" {
+			if pe, ok := err.(*ParseError); !ok || pe.Kind != UnknownKeyword {
+				t.Errorf("%q: got %v", args, err)
+			}
+		} else if err != nil {
+			t.Errorf("%q: %v", args, err)
+		} else if got := strings.Join(s.Path(), " "); got != want {
+			t.Errorf("%q: selected %q", args, got)
+		}
+	}
+	if err := keywordsTree(false).Parse([]string{"rm", "mv"}); err != nil {
+		t.Errorf("alias not parsed: %v", err)
+	}
+}
+
+func TestKeywordPrefixes(t *testing.T) {
+	cases := []struct {
+		args        string
+		want        string
+		kind        ErrorKind
+		suggestions string
+	}{
+		{"rem a", "remote add", 0, ""},
+		{"r add", "remote add", 0, ""},
+		{"remote ren", "remote rename", 0, ""},
+		{"remote m", "remote rename", 0, ""},
+		{"stat", "status", 0, ""},
+		{"remote re", "", AmbiguousKeyword, "remove,rename"},
+		{"remote x", "", UnknownKeyword, ""},
+		{"remote renme", "", UnknownKeyword, "rename"},
+		{"remote rew", "", UnknownKeyword, ""},
+	}
+	for _, c := range cases {
+		s, err := keywordsTree(true).Select(strings.Fields(c.args)...)
+		if c.want != "" {
+			if err != nil {
+				t.Errorf("%q: %v", c.args, err)
+			} else if got := strings.Join(s.Path(), " "); got != c.want {
+				t.Errorf("%q: selected %q", c.args, got)
+			}
+			continue
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Kind != c.kind {
+			t.Errorf("%q: got %v", c.args, err)
+		} else if strings.Join(pe.Suggestions, ",") != c.suggestions {
+			t.Errorf("%q: suggestions %q", c.args, pe.Suggestions)
+		}
+	}
+}
+
+func TestPrefixMatchingInherited(t *testing.T) {
+	root := keywordsTree(false)
+	root.subSets["remote"].PrefixMatching = true
+	if err := root.Parse([]string{"remote", "ad"}); err != nil {
+		t.Errorf("got %v", err)
+	}
+	if err := root.Parse([]string{"stat"}); err == nil {
+		t.Errorf("prefix matching enabled above the FlagSet setting it")
+	}
+}
+
+func TestKeywordClash(t *testing.T) {
+	cases := []struct {
+		name    string
+		aliases []string
+	}{
+		{"remote", nil},
+		{"rm", nil},
+		{"rmdir", []string{"rm"}},
+		{"stash", []string{"status"}},
+		{"stash", []string{"sh", "sh"}},
+		{"stash", []string{"stash"}},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s %q accepted", c.name, c.aliases)
+				}
+			}()
+			keywordsTree(false).NewFlagSet(c.name, "", c.aliases...)
+		}()
+	}
+	root := keywordsTree(false)
+	root.NewFlagSet("stash", "", "sh")
+	if s, err := root.Select("sh"); err != nil || s.Name() != "stash" {
+		t.Errorf("got %v", err)
+	}
+}
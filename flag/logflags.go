@@ -0,0 +1,149 @@
+// The log flags set the level of gu_log.STD_LOGGER, raised by one for
+// every -v, and enable gu_log.DebugLog at a debug sub-level, like the
+// debug7 to debug9 ones of the throttler; they are persistent, and are
+// applied by a PreRun hook once the command line has been parsed
+package gu_flag
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/maxcalandrelli/goutil/log"
+)
+
+const (
+	LOG_LEVEL_FLAG = "log-level"
+	VERBOSE_FLAG   = "v"
+	DEBUG_LOG_FLAG = "debug-log"
+)
+
+type logLevelValue struct {
+	target  *gu_log.LogLevel
+	initial gu_log.LogLevel
+	factory func(*FlagSet, string)
+}
+
+func levelName(l gu_log.LogLevel) string {
+	if l > gu_log.LOG_DEBUG {
+		return fmt.Sprintf("debug%d", l-gu_log.LOG_DEBUG)
+	}
+	return strings.ToLower(l.String())
+}
+
+func (lv *logLevelValue) Set(v string) error {
+	return lv.target.Set(v)
+}
+
+func (lv *logLevelValue) String() string {
+	if lv == nil || lv.target == nil {
+		return ""
+	}
+	return levelName(*lv.target)
+}
+
+func (lv *logLevelValue) Get() interface{} {
+	return *lv.target
+}
+
+func (lv *logLevelValue) restoreDefault() {
+	*lv.target = lv.initial
+}
+
+func (lv *logLevelValue) redefine(c *FlagSet, name string) {
+	lv.factory(c, name)
+}
+
+func (f *FlagSet) logLevelVar(name string, value gu_log.LogLevel, usage string) {
+	lv := &logLevelValue{target: new(gu_log.LogLevel), initial: value}
+	lv.factory = func(c *FlagSet, name string) {
+		c.logLevelVar(name, value, usage)
+	}
+	*lv.target = value
+	f.Flags.Var(lv, name, usage)
+}
+
+// verbosityValue counts the times it is given as a boolean flag, and
+// can also be given a count
+type verbosityValue struct {
+	target *int
+	usage  string
+}
+
+func (vv *verbosityValue) Set(v string) error {
+	switch v {
+	case "true":
+		*vv.target++
+	case "false":
+		*vv.target = 0
+	default:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return numberError(err)
+		}
+		if n < 0 {
+			return OUT_OF_RANGE
+		}
+		*vv.target = n
+	}
+	return nil
+}
+
+func (vv *verbosityValue) String() string {
+	if vv == nil || vv.target == nil {
+		return "0"
+	}
+	return strconv.Itoa(*vv.target)
+}
+
+func (vv *verbosityValue) Get() interface{} {
+	return *vv.target
+}
+
+func (vv *verbosityValue) IsBoolFlag() bool {
+	return true
+}
+
+func (vv *verbosityValue) restoreDefault() {
+	*vv.target = 0
+}
+
+func (vv *verbosityValue) redefine(c *FlagSet, name string) {
+	c.Flags.Var(&verbosityValue{target: new(int), usage: vv.usage}, name, vv.usage)
+}
+
+// AddLogFlags defines the persistent -log-level, -v and -debug-log
+// flags, the default level being the current one of gu_log.STD_LOGGER,
+// and chains a PreRun hook applying them before the PreRun already set
+func (f *FlagSet) AddLogFlags() {
+	f.logLevelVar(LOG_LEVEL_FLAG, gu_log.STD_LOGGER.GetCurrentLevel(),
+		"level of the logged messages: error, warning, info, debug or debugN")
+	usage := "raise the log level by one, can be repeated"
+	f.Flags.Var(&verbosityValue{target: new(int), usage: usage}, VERBOSE_FLAG, usage)
+	f.logLevelVar(DEBUG_LOG_FLAG, gu_log.LOG_DEBUG,
+		"enable the debug log up to the given level, as in debug8")
+	f.Persist(LOG_LEVEL_FLAG, VERBOSE_FLAG, DEBUG_LOG_FLAG)
+	next := f.PreRun
+	f.PreRun = func(ctx context.Context, self *FlagSet) (context.Context, error) {
+		self.ApplyLogFlags()
+		if next != nil {
+			return next(ctx, self)
+		}
+		return ctx, nil
+	}
+}
+
+// ApplyLogFlags configures gu_log.STD_LOGGER and, if -debug-log was
+// given, gu_log.DebugLog, replacing the NIL_LOGGER with a standard one
+func (f *FlagSet) ApplyLogFlags() {
+	level, _ := f.Value(LOG_LEVEL_FLAG).(gu_log.LogLevel)
+	verbosity, _ := f.Value(VERBOSE_FLAG).(int)
+	gu_log.STD_LOGGER.SetLevel(level + gu_log.LogLevel(verbosity))
+	if f.isSet(DEBUG_LOG_FLAG) {
+		if gu_log.DebugLog == gu_log.NIL_LOGGER {
+			gu_log.DebugLog = gu_log.StdLogger()
+		}
+		gu_log.DebugLog.SetLevel(f.Value(DEBUG_LOG_FLAG).(gu_log.LogLevel))
+	}
+}
@@ -0,0 +1,81 @@
+package gu_flag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maxcalandrelli/goutil/log"
+)
+
+// keepLoggers restores the loggers changed by ApplyLogFlags
+func keepLoggers(t *testing.T) {
+	level, debugLog := gu_log.STD_LOGGER.GetCurrentLevel(), gu_log.DebugLog
+	t.Cleanup(func() {
+		gu_log.STD_LOGGER.SetLevel(level)
+		gu_log.DebugLog = debugLog
+	})
+}
+
+func logFlagsTree(gnu bool) *FlagSet {
+	root := NewRoot("prog", "")
+	root.GNUStyle = gnu
+	root.AddLogFlags()
+	root.NewFlagSet("run", "")
+	return root
+}
+
+func TestLogFlags(t *testing.T) {
+	keepLoggers(t)
+	gu_log.STD_LOGGER.SetLevel(gu_log.LOG_WARNING)
+	cases := []struct {
+		args  string
+		gnu   bool
+		level gu_log.LogLevel
+	}{
+		{"run", false, gu_log.LOG_WARNING},
+		{"-log-level=error run", false, gu_log.LOG_ERROR},
+		{"run -log-level=debug3", false, gu_log.LOG_DEBUG + 3},
+		{"-v run -v", false, gu_log.LOG_DEBUG},
+		{"-v=5 run", false, gu_log.LOG_WARNING + 5},
+		{"-vv run", true, gu_log.LOG_DEBUG},
+		{"--log-level info run -vvv", true, gu_log.LOG_DEBUG + 2},
+	}
+	for _, c := range cases {
+		gu_log.STD_LOGGER.SetLevel(gu_log.LOG_WARNING)
+		root := logFlagsTree(c.gnu)
+		if err := root.Parse(strings.Fields(c.args)); err != nil {
+			t.Errorf("%q: %v", c.args, err)
+		} else if l := gu_log.STD_LOGGER.GetCurrentLevel(); l != c.level {
+			t.Errorf("%q: got level %v, want %v", c.args, l, c.level)
+		}
+	}
+}
+
+func TestDebugLogFlag(t *testing.T) {
+	keepLoggers(t)
+	gu_log.DebugLog = gu_log.NIL_LOGGER
+	root := logFlagsTree(false)
+	if err := root.Parse([]string{"run"}); err != nil || gu_log.DebugLog != gu_log.NIL_LOGGER {
+		t.Errorf("debug log enabled without -debug-log: %v", err)
+	}
+	if err := root.Parse([]string{"run", "-debug-log=debug8"}); err != nil {
+		t.Fatal(err)
+	}
+	if gu_log.DebugLog == gu_log.NIL_LOGGER || gu_log.DebugLog.GetCurrentLevel() != gu_log.LOG_DEBUG+8 {
+		t.Errorf("debug log not enabled at debug8")
+	}
+	if v := root.Flags.Lookup(DEBUG_LOG_FLAG).Value.String(); v != "debug8" {
+		t.Errorf("got %s", v)
+	}
+}
+
+func TestLogFlagsErrors(t *testing.T) {
+	keepLoggers(t)
+	for _, args := range []string{"-log-level=loud run", "-v=-1 run", "-v=x run"} {
+		var pe *ParseError
+		if err := logFlagsTree(false).Parse(strings.Fields(args)); !errors.As(err, &pe) || pe.Kind != BadFlagValue {
+			t.Errorf("%q: got %v", args, err)
+		}
+	}
+}
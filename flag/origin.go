@@ -0,0 +1,66 @@
+// Every flag value comes from some source; knowing which one allows
+// lower priority sources to leave alone values given explicitly
+package gu_flag
+
+type Origin int
+
+const (
+	OriginDefault Origin = iota
+	OriginCommandLine
+	OriginEnvironment
+	OriginConfigFile
+)
+
+func (o Origin) String() string {
+	switch o {
+	case OriginCommandLine:
+		return "command line"
+	case OriginEnvironment:
+		return "environment"
+	case OriginConfigFile:
+		return "config file"
+	}
+	return "default"
+}
+
+// Origin tells where the current value of the named flag comes from
+func (f *FlagSet) Origin(name string) Origin {
+	o := f.owner(name)
+	return o.origins[o.canonicalFlag(name)]
+}
+
+// setOrigin records the origin on the FlagSet defining the flag, so that
+// persistent flags given at a deeper level are seen as set
+func (f *FlagSet) setOrigin(name string, origin Origin) {
+	o := f.owner(name)
+	if o.origins == nil {
+		o.origins = map[string]Origin{}
+	}
+	o.origins[o.canonicalFlag(name)] = origin
+}
+
+func (f *FlagSet) isSet(name string) bool {
+	return f.Origin(name) != OriginDefault
+}
+
+// setFromSource sets a flag from a source other than the command line,
+// checking deprecation as the command line does; the values of a
+// deprecated flag go to its replacement too, unless a source with higher
+// priority already set it
+func (f *FlagSet) setFromSource(token, name string, o Origin, values ...string) error {
+	if err := f.checkDeprecatedFlag(token, name); err != nil {
+		return err
+	}
+	owner := f.owner(name)
+	for _, v := range values {
+		if err := owner.Flags.Set(name, v); err != nil {
+			return err
+		}
+	}
+	f.setOrigin(name, o)
+	d := owner.deprecated[owner.canonicalFlag(name)]
+	if d == nil || len(d.ReplacedBy) == 0 || owner.isSet(d.ReplacedBy) {
+		return nil
+	}
+	return owner.setFromSource(token, d.ReplacedBy, o, values...)
+}
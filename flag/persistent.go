@@ -0,0 +1,80 @@
+// Persistent flags are accepted after the keyword defining them and at
+// any level below it, always setting the variable of the defining level
+package gu_flag
+
+import (
+	"flag"
+	"sort"
+)
+
+// Persist marks the named flags, already defined, as inherited by all
+// the subgrammars below this one
+func (f *FlagSet) Persist(names ...string) {
+	if f.persistent == nil {
+		f.persistent = map[string]bool{}
+	}
+	for _, n := range names {
+		f.persistent[n] = true
+	}
+}
+
+func (f *FlagSet) IsPersistent(name string) bool {
+	return f.persistent[name]
+}
+
+// lineage returns the FlagSets from the root down to this one
+func (f *FlagSet) lineage() []*FlagSet {
+	r := []*FlagSet{}
+	for s := f; s != nil; s = s.parent {
+		r = append([]*FlagSet{s}, r...)
+	}
+	return r
+}
+
+// lookup finds the named flag among the ones of this FlagSet and the
+// persistent ones of the keywords above it, the nearest first, and
+// returns it with the FlagSet defining it; inherited flags are resolved
+// at every lookup, and never added to Flags
+func (f *FlagSet) lookup(name string) (*flag.Flag, *FlagSet) {
+	if fl := f.Flags.Lookup(name); fl != nil {
+		return fl, f
+	}
+	for p := f.parent; p != nil; p = p.parent {
+		if fl := p.Flags.Lookup(name); fl != nil && p.persistent[p.canonicalFlag(name)] {
+			return fl, p
+		}
+	}
+	return nil, f
+}
+
+// visitAll visits the flags of this FlagSet, and then the persistent
+// flags it inherits, each group in name order
+func (f *FlagSet) visitAll(visit func(*flag.Flag)) {
+	f.Flags.VisitAll(visit)
+	inherited := map[string]*flag.Flag{}
+	for p := f.parent; p != nil; p = p.parent {
+		p.Flags.VisitAll(func(fl *flag.Flag) {
+			if _, found := inherited[fl.Name]; !found && p.persistent[p.canonicalFlag(fl.Name)] && f.Flags.Lookup(fl.Name) == nil {
+				inherited[fl.Name] = fl
+			}
+		})
+	}
+	names := []string{}
+	for n, _ := range inherited {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		visit(inherited[n])
+	}
+}
+
+// owner returns the FlagSet where the named flag has been defined
+func (f *FlagSet) owner(name string) *FlagSet {
+	_, o := f.lookup(name)
+	return o
+}
+
+func (f *FlagSet) isInherited(fl *flag.Flag) bool {
+	return f.Flags.Lookup(fl.Name) != fl
+}
@@ -0,0 +1,75 @@
+package gu_flag
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func persistentTree() (root, sub, leaf *FlagSet, verbose *bool, name *string) {
+	root = NewRoot("prog", "")
+	verbose = root.Flags.Bool("verbose", false, "be verbose")
+	root.Alias("V", "verbose")
+	root.Persist("verbose")
+	sub = root.NewFlagSet("sub", "")
+	name = sub.Flags.String("name", "", "a name")
+	sub.Persist("name")
+	leaf = sub.NewFlagSet("leaf", "")
+	return
+}
+
+func TestPersistentFlagsBelow(t *testing.T) {
+	root, sub, _, verbose, name := persistentTree()
+	if err := root.Parse([]string{"sub", "leaf", "-V", "-name=x"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*verbose || *name != "x" {
+		t.Errorf("got verbose=%v name=%q", *verbose, *name)
+	}
+	if o := root.Origin("verbose"); o != OriginCommandLine {
+		t.Errorf("origin of -verbose at the root is %v", o)
+	}
+	if sub.Flags.Lookup("verbose") != nil {
+		t.Errorf("inherited flag added to the Flags of a keyword")
+	}
+}
+
+func TestPersistentFlagsNotCopied(t *testing.T) {
+	root, sub, leaf, _, _ := persistentTree()
+	var out bytes.Buffer
+	root.SetOutput(&out)
+	root.Parse([]string{"sub", "leaf", "-verbose"})
+	leaf.PrintHelp()
+	root.Clone()
+	root.Export()
+	for _, s := range []*FlagSet{sub, leaf} {
+		s.Flags.VisitAll(func(fl *flag.Flag) {
+			if fl.Name == "verbose" || fl.Name == "V" {
+				t.Errorf("%s: inherited -%s found in Flags", s.Name(), fl.Name)
+			}
+		})
+	}
+	leaf.Flags.Bool("verbose", false, "shadowing flag")
+	root.Reset()
+	if err := root.Parse([]string{"sub", "leaf", "-verbose"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := root.Value("verbose"); v != false {
+		t.Errorf("shadowed flag set at the root: %v", v)
+	}
+}
+
+func TestPersistentFlagsHelp(t *testing.T) {
+	_, _, leaf, _, _ := persistentTree()
+	var out bytes.Buffer
+	leaf.WriteHelp(&out)
+	help := out.String()
+	i := strings.Index(help, "Persistent flags:")
+	if i < 0 || !strings.Contains(help[i:], "-V, -verbose") || !strings.Contains(help[i:], "-name") {
+		t.Errorf("persistent flags not listed:\n%s", help)
+	}
+	if strings.Contains(help, "Global flags") {
+		t.Errorf("persistent flags listed twice:\n%s", help)
+	}
+}
@@ -0,0 +1,166 @@
+// Plugins extend a FlagSet with external commands, the way git does:
+// once enabled, any executable named <prog>-<keyword>, or
+// <prog>-<path>-<keyword> below the root, found in the plugin
+// directories or in PATH becomes a keyword, unless a keyword with the
+// same name or alias is already defined. The plugin is run with the args
+// following its keyword, untouched, and with these variables added to
+// the environment:
+//   - GU_PLUGIN_PROGRAM, the name of the program
+//   - GU_PLUGIN_KEYWORDS, the keyword path, plugin keyword included,
+//     separated by spaces
+//   - GU_FLAG_<PATH>_<NAME>, the value of every flag of the keywords
+//     above, named as by EnvName without prefix, as in GU_FLAG_VERBOSE
+//     or GU_FLAG_HAT_COLOR_INDEX
+//
+// The exit status of the plugin is returned as an ExitCode; its standard
+// output goes to the writer set by SetPluginOutput, os.Stdout by default,
+// and its standard error to the output of the FlagSet
+package gu_flag
+
+import (
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	PLUGIN_ENV_PROGRAM  = "GU_PLUGIN_PROGRAM"
+	PLUGIN_ENV_KEYWORDS = "GU_PLUGIN_KEYWORDS"
+	PLUGIN_ENV_FLAG     = "GU_FLAG"
+)
+
+// EnablePlugins looks for plugins below this FlagSet in dirs first, and
+// then in PATH; the lookup is made at the first need, and done again
+// after every call, dropping the plugins found before
+func (f *FlagSet) EnablePlugins(dirs ...string) {
+	for k, s := range f.subSets {
+		if len(s.plugin) > 0 {
+			delete(f.subSets, k)
+		}
+	}
+	f.pluginsEnabled = true
+	f.pluginsLoaded = false
+	f.pluginDirs = dirs
+}
+
+// SetPluginOutput sets the writer receiving the standard output of the
+// plugins below this FlagSet
+func (f *FlagSet) SetPluginOutput(w io.Writer) {
+	f.pluginOutput = w
+}
+
+func (f *FlagSet) PluginOutput() io.Writer {
+	for s := f; s != nil; s = s.parent {
+		if s.pluginOutput != nil {
+			return s.pluginOutput
+		}
+	}
+	return os.Stdout
+}
+
+// Plugin returns the executable run by this keyword, if it is a plugin
+func (f *FlagSet) Plugin() string {
+	return f.plugin
+}
+
+func (f *FlagSet) pluginPrefix() string {
+	return strings.Join(append([]string{f.ProgramName()}, f.Path()...), "-") + "-"
+}
+
+// pluginKeyword returns the keyword provided by the file named name, if
+// it is an executable plugin not belonging to a keyword below, having
+// plugins enabled
+func (f *FlagSet) pluginKeyword(dir, name string) (string, bool) {
+	prefix := f.pluginPrefix()
+	if !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+	keyword := strings.TrimPrefix(name, prefix)
+	fi, err := os.Stat(filepath.Join(dir, name))
+	if err != nil || fi.IsDir() {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		ext := filepath.Ext(keyword)
+		if !strings.EqualFold(ext, ".exe") {
+			return "", false
+		}
+		keyword = strings.TrimSuffix(keyword, ext)
+	} else if fi.Mode()&0111 == 0 {
+		return "", false
+	}
+	for k, s := range f.subSets {
+		if s.pluginsEnabled && strings.HasPrefix(keyword, k+"-") {
+			return "", false
+		}
+	}
+	return keyword, len(keyword) > 0
+}
+
+// loadPlugins adds the plugins found to the keywords, the ones in the
+// earlier directories shadowing the others; the directories are read
+// only when a token is not a keyword defined, when there are no keywords
+// defined at all, or when the keywords are listed, as for help and
+// completion
+func (f *FlagSet) loadPlugins() {
+	if !f.pluginsEnabled || f.pluginsLoaded {
+		return
+	}
+	f.pluginsLoaded = true
+	dirs := append(append([]string{}, f.pluginDirs...), filepath.SplitList(os.Getenv("PATH"))...)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			keyword, ok := f.pluginKeyword(dir, e.Name())
+			if _, defined := f.keywordOwner(keyword); !ok || defined {
+				continue
+			}
+			s := f.NewFlagSet(keyword, "external command "+e.Name())
+			s.plugin = filepath.Join(dir, e.Name())
+			s.NonKeywordArgs = true
+			s.HookFunc = func(self *FlagSet) error {
+				return self.runPlugin()
+			}
+		}
+	}
+}
+
+// pluginEnv describes the keyword path and the flags above the plugin
+func (f *FlagSet) pluginEnv() []string {
+	r := []string{
+		PLUGIN_ENV_PROGRAM + "=" + f.ProgramName(),
+		PLUGIN_ENV_KEYWORDS + "=" + strings.Join(f.Path(), " "),
+	}
+	for p := f.parent; p != nil; p = p.parent {
+		p.Flags.VisitAll(func(fl *flag.Flag) {
+			if _, isAlias := p.aliases[fl.Name]; isAlias {
+				return
+			}
+			parts := append([]string{PLUGIN_ENV_FLAG}, append(p.Path(), fl.Name)...)
+			name := strings.ToUpper(envReplacer.Replace(strings.Join(parts, "_")))
+			r = append(r, name+"="+fl.Value.String())
+		})
+	}
+	return r
+}
+
+func (f *FlagSet) runPlugin() error {
+	cmd := exec.CommandContext(f.Context(), f.plugin, f.args...)
+	cmd.Env = append(os.Environ(), f.pluginEnv()...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, f.PluginOutput(), f.Output()
+	err := cmd.Run()
+	if ee, ok := err.(*exec.ExitError); ok {
+		if ee.ExitCode() < 0 {
+			return &ExitCode{Code: EXIT_FAILURE, Err: err}
+		}
+		return &ExitCode{Code: ee.ExitCode()}
+	}
+	return err
+}
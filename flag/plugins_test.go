@@ -0,0 +1,106 @@
+package gu_flag
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pluginDirs(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	t.Setenv("PATH", t.TempDir())
+	writePlugin(t, first, "prog-hello", `echo "$GU_PLUGIN_PROGRAM|$GU_PLUGIN_KEYWORDS|$GU_FLAG_VERBOSE|$*"`)
+	writePlugin(t, first, "prog-fail", `echo oops >&2; exit 5`)
+	writePlugin(t, second, "prog-hello", `echo shadowed`)
+	writePlugin(t, second, "prog-remote-add", `echo "$GU_FLAG_REMOTE_NAME|$*"`)
+	os.WriteFile(filepath.Join(second, "prog-readme"), []byte("not a plugin"), 0644)
+	return first, second
+}
+
+func TestPlugins(t *testing.T) {
+	first, second := pluginDirs(t)
+	root := NewRoot("prog", "")
+	root.Flags.Bool("verbose", false, "")
+	remote := root.NewFlagSet("remote", "")
+	remote.Flags.String("name", "origin", "")
+	root.EnablePlugins(first, second)
+	remote.EnablePlugins(second)
+	var out, errs bytes.Buffer
+	root.SetPluginOutput(&out)
+	root.SetOutput(&errs)
+	if got := strings.Join(root.Keywords(), ","); got != "fail,hello,remote" {
+		t.Errorf("got keywords %s", got)
+	}
+	if err := root.Parse([]string{"-verbose", "hello", "-x", "y"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Parse([]string{"remote", "-name=up", "add", "z"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "prog|hello|true|-x y\nup|z\n" {
+		t.Errorf("got plugin output %q", got)
+	}
+	var ec *ExitCode
+	if err := root.Parse([]string{"fail"}); !errors.As(err, &ec) || ec.Code != 5 || errs.String() != "oops\n" {
+		t.Errorf("got %v, stderr %q", err, errs.String())
+	}
+	if p := root.subSets["hello"].Plugin(); p != filepath.Join(first, "prog-hello") {
+		t.Errorf("got plugin %s", p)
+	}
+}
+
+func TestPluginsReload(t *testing.T) {
+	first, second := pluginDirs(t)
+	root := NewRoot("prog", "")
+	root.NewFlagSet("fail", "a keyword shadowing a plugin")
+	root.EnablePlugins(first)
+	if got := strings.Join(root.Keywords(), ","); got != "fail,hello" {
+		t.Errorf("got keywords %s", got)
+	}
+	if root.subSets["fail"].Plugin() != "" {
+		t.Errorf("keyword replaced by a plugin")
+	}
+	root.EnablePlugins(second)
+	if got := strings.Join(root.Keywords(), ","); got != "fail,hello,remote-add" {
+		t.Errorf("after reload got keywords %s", got)
+	}
+	if p := root.subSets["hello"].Plugin(); p != filepath.Join(second, "prog-hello") {
+		t.Errorf("plugin of the old dirs kept: %s", p)
+	}
+	if _, found := root.subSets["fail"]; !found {
+		t.Errorf("keyword dropped on reload")
+	}
+}
+
+func TestPluginsLoadedLazily(t *testing.T) {
+	first, _ := pluginDirs(t)
+	root := NewRoot("prog", "")
+	root.NewFlagSet("remote", "")
+	root.EnablePlugins(first)
+	root.SetPluginOutput(&bytes.Buffer{})
+	if err := root.Parse([]string{"remote"}); err != nil {
+		t.Fatal(err)
+	}
+	root.Reset()
+	if root.pluginsLoaded {
+		t.Errorf("plugins looked for with the keyword defined")
+	}
+	if err := root.Parse([]string{"hello"}); err != nil || !root.pluginsLoaded {
+		t.Errorf("plugin not found: %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+// Positional args of a leaf subgrammar can be declared by name, each
+// with its arity, validators and typed destination; usage renders them
+// as in "prog copy <src> <dst>..."
+package gu_flag
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type PositionalArg struct {
+	name       string
+	usage      string
+	min        int
+	max        int
+	validators []func(string) error
+	store      func(string) error
+	reset      func()
+	values     []string
+	factory    func(*FlagSet) *PositionalArg
+	owner      *FlagSet
+}
+
+func (pa *PositionalArg) Name() string {
+	return pa.name
+}
+
+func (pa *PositionalArg) Usage() string {
+	return pa.usage
+}
+
+// Validate adds a check run on every value given for the arg
+func (pa *PositionalArg) Validate(validator func(string) error) *PositionalArg {
+	pa.validators = append(pa.validators, validator)
+	return pa
+}
+
+// Optional allows a single valued arg to be omitted; it panics if a
+// required arg has already been declared after this one
+func (pa *PositionalArg) Optional() *PositionalArg {
+	after := false
+	for _, next := range pa.owner.positionals {
+		if after && next.min > 0 {
+			panic(fmt.Sprintf("positional arg <%s> made optional before required <%s>", pa.name, next.name))
+		}
+		after = after || next == pa
+	}
+	pa.min = 0
+	return pa
+}
+
+func (pa *PositionalArg) set(v string) error {
+	for _, validate := range pa.validators {
+		if err := validate(v); err != nil {
+			return err
+		}
+	}
+	if err := pa.store(v); err != nil {
+		return err
+	}
+	pa.values = append(pa.values, v)
+	return nil
+}
+
+// Values returns the args assigned in the last parse
+func (pa *PositionalArg) Values() []string {
+	return pa.values
+}
+
+// clear empties the arg, restoring the destination of single valued ones
+func (pa *PositionalArg) clear() {
+	pa.reset()
+	pa.values = nil
+}
+
+func (pa *PositionalArg) String() string {
+	r := "<" + pa.name + ">"
+	if pa.max != 1 {
+		r += "..."
+	}
+	if pa.min == 0 {
+		r = "[" + r + "]"
+	}
+	return r
+}
+
+// FileExists is a validator for args naming existing files
+func FileExists(path string) error {
+	_, err := os.Stat(path)
+	return err
+}
+
+// addPositional panics on declarations that could never be satisfied
+// or assigned unambiguously. No required arg can follow an optional one:
+// single valued args declared after an optional one are optional too
+func (f *FlagSet) addPositional(name, usage string, min, max int, store func(string) error, reset func()) *PositionalArg {
+	if min < 0 || max == 0 || (max > 0 && min > max) {
+		panic(fmt.Sprintf("positional arg <%s> declared with bad arity %d..%d", name, min, max))
+	}
+	if n := len(f.positionals); n > 0 {
+		prev := f.positionals[n-1]
+		if prev.max != 1 {
+			panic(fmt.Sprintf("positional arg <%s> declared after variadic <%s>", name, prev.name))
+		}
+		if prev.min == 0 && max == 1 {
+			min = 0
+		} else if prev.min == 0 && min > 0 {
+			panic(fmt.Sprintf("required positional arg <%s> declared after optional <%s>", name, prev.name))
+		}
+	}
+	pa := &PositionalArg{name: name, usage: usage, min: min, max: max, store: store, reset: reset, owner: f}
+	f.positionals = append(f.positionals, pa)
+	f.NonKeywordArgs = true
+	return pa
+}
+
+func (f *FlagSet) StringArg(p *string, name, usage string) *PositionalArg {
+	initial := *p
+	pa := f.addPositional(name, usage, 1, 1,
+		func(v string) error { *p = v; return nil },
+		func() { *p = initial },
+	)
+	pa.factory = func(c *FlagSet) *PositionalArg { return c.StringArg(new(string), name, usage) }
+	return pa
+}
+
+func (f *FlagSet) IntArg(p *int, name, usage string) *PositionalArg {
+	initial := *p
+	pa := f.addPositional(name, usage, 1, 1,
+		func(v string) error {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return numberError(err)
+			}
+			*p = i
+			return nil
+		},
+		func() { *p = initial },
+	)
+	pa.factory = func(c *FlagSet) *PositionalArg { return c.IntArg(new(int), name, usage) }
+	return pa
+}
+
+func (f *FlagSet) FileArg(p *string, name, usage string) *PositionalArg {
+	return f.StringArg(p, name, usage).Validate(FileExists)
+}
+
+// StringsArg declares a trailing arg taking from min to max values; a
+// negative max means no upper limit
+func (f *FlagSet) StringsArg(p *[]string, name, usage string, min, max int) *PositionalArg {
+	pa := f.addPositional(name, usage, min, max,
+		func(v string) error { *p = append(*p, v); return nil },
+		func() { *p = []string{} },
+	)
+	pa.factory = func(c *FlagSet) *PositionalArg { return c.StringsArg(new([]string), name, usage, min, max) }
+	return pa
+}
+
+func (f *FlagSet) IntsArg(p *[]int, name, usage string, min, max int) *PositionalArg {
+	pa := f.addPositional(name, usage, min, max,
+		func(v string) error {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return numberError(err)
+			}
+			*p = append(*p, i)
+			return nil
+		},
+		func() { *p = []int{} },
+	)
+	pa.factory = func(c *FlagSet) *PositionalArg { return c.IntsArg(new([]int), name, usage, min, max) }
+	return pa
+}
+
+func (f *FlagSet) Positionals() []*PositionalArg {
+	return f.positionals
+}
+
+func (f *FlagSet) positionalsUsage() string {
+	r := []string{}
+	for _, pa := range f.positionals {
+		r = append(r, pa.String())
+	}
+	return strings.Join(r, " ")
+}
+
+// assignPositionals distributes args among the declared positionals:
+// each one gets its minimum, and the remaining args go to the first
+// ones able to take them
+func (f *FlagSet) assignPositionals(args []string) error {
+	counts := make([]int, len(f.positionals))
+	available := len(args)
+	for i, pa := range f.positionals {
+		if available < pa.min {
+			return f.parseError(MissingPositional, pa.String(), nil)
+		}
+		counts[i] = pa.min
+		available -= pa.min
+	}
+	for i, pa := range f.positionals {
+		extra := available
+		if pa.max >= 0 && extra > pa.max-pa.min {
+			extra = pa.max - pa.min
+		}
+		counts[i] += extra
+		available -= extra
+	}
+	if available > 0 {
+		pe := f.parseError(UnexpectedPositional, args[len(args)-available], nil)
+		if !f.gnuStyle() {
+			pe.argsLeft = available
+		}
+		return pe
+	}
+	for i, pa := range f.positionals {
+		pa.clear()
+		for j, v := range args[:counts[i]] {
+			if err := pa.set(v); err != nil {
+				pe := f.parseError(BadPositionalValue, pa.String(), err)
+				pe.Value = v
+				if !f.gnuStyle() {
+					pe.argsLeft = len(args) - j
+				}
+				return pe
+			}
+		}
+		args = args[counts[i]:]
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package gu_flag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPositionals(t *testing.T) {
+	cases := []struct {
+		args  string
+		kind  ErrorKind
+		src   string
+		count int
+		files []string
+	}{
+		{"a", 0, "a", -1, nil},
+		{"a 3", 0, "a", 3, nil},
+		{"a 3 x y", 0, "a", 3, []string{"x", "y"}},
+		{"", MissingPositional, "", 0, nil},
+		{"a x", BadPositionalValue, "", 0, nil},
+	}
+	for _, c := range cases {
+		f := NewRoot("prog", "")
+		var src string
+		count := -1
+		var files []string
+		f.StringArg(&src, "src", "")
+		f.IntArg(&count, "count", "").Optional()
+		f.StringsArg(&files, "files", "", 0, -1)
+		err := f.Parse(strings.Fields(c.args))
+		if c.kind != 0 {
+			var pe *ParseError
+			if !errors.As(err, &pe) || pe.Kind != c.kind {
+				t.Errorf("%q: got %v, want %v", c.args, err, c.kind)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", c.args, err)
+			continue
+		}
+		if src != c.src || count != c.count || strings.Join(files, " ") != strings.Join(c.files, " ") {
+			t.Errorf("%q: got %q %d %q", c.args, src, count, files)
+		}
+	}
+}
+
+func TestPositionalsUnexpected(t *testing.T) {
+	f := NewRoot("prog", "")
+	var a, b string
+	f.StringArg(&a, "a", "")
+	f.StringArg(&b, "b", "").Optional()
+	var pe *ParseError
+	if err := f.Parse([]string{"x", "y", "z"}); !errors.As(err, &pe) || pe.Kind != UnexpectedPositional || pe.Token != "z" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestPositionalDeclarationPanics(t *testing.T) {
+	var s string
+	var ss []string
+	cases := map[string]func(f *FlagSet){
+		"min > max": func(f *FlagSet) { f.StringsArg(&ss, "x", "", 3, 2) },
+		"max == 0":  func(f *FlagSet) { f.StringsArg(&ss, "x", "", 0, 0) },
+		"min < 0":   func(f *FlagSet) { f.StringsArg(&ss, "x", "", -1, -1) },
+		"after variadic": func(f *FlagSet) {
+			f.StringsArg(&ss, "x", "", 1, 2)
+			f.StringArg(&s, "y", "")
+		},
+		"made optional before a required arg": func(f *FlagSet) {
+			x := f.StringArg(&s, "x", "")
+			f.StringArg(&s, "y", "")
+			x.Optional()
+		},
+		"variadic required after optional": func(f *FlagSet) {
+			f.StringArg(&s, "x", "").Optional()
+			f.StringsArg(&ss, "y", "", 1, -1)
+		},
+	}
+	for name, declare := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			declare(NewRoot("prog", ""))
+		}()
+	}
+	f := NewRoot("prog", "")
+	f.StringArg(&s, "x", "")
+	f.StringArg(&s, "y", "").Optional()
+	f.StringsArg(&ss, "z", "", 0, 2)
+	if err := f.Parse([]string{"a"}); err != nil {
+		t.Error(err)
+	}
+	var x, y string
+	f = NewRoot("prog", "")
+	f.StringArg(&x, "x", "").Optional()
+	f.StringArg(&y, "y", "")
+	if f.UsageLine() != "prog [<x>] [<y>]" {
+		t.Errorf("got usage %s", f.UsageLine())
+	}
+	if err := f.Parse([]string{"a"}); err != nil || x != "a" || y != "" {
+		t.Errorf("got %q %q, %v", x, y, err)
+	}
+}
@@ -0,0 +1,400 @@
+// Size flags take human byte counts, as in -chunk=64KiB or -max=1.5GB,
+// and rate flags take an amount of units per time unit, as in
+// -bw=10MB/s or -calls=300req/min:both, or a duty cycle, as in
+// -load=50%duty; a rate flag can also define or update a throttled
+// quantity of a gu_time.Throttler after every successful parse
+package gu_flag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxcalandrelli/goutil/time"
+)
+
+const (
+	RATE_DUTY_SUFFIX = "%duty"
+	RATE_MODE_SEP    = ":"
+)
+
+var (
+	BAD_SIZE         error = errors.New("not a size")
+	BAD_RATE         error = errors.New("not a rate")
+	MISSING_INTERVAL error = errors.New("missing time unit")
+	UNKNOWN_MODE     error = errors.New("unknown throttle mode, expected avg, instant or both")
+	OUT_OF_RANGE     error = errors.New("value out of range")
+	FIXED_RATE       error = errors.New("the maximum rate of the quantity cannot be changed")
+)
+
+// sizeUnits are sorted from the largest, so that sizes are formatted
+// with the largest unit dividing them
+var sizeUnits = []struct {
+	name string
+	size int64
+}{
+	{"PiB", 1 << 50}, {"PB", 1e15},
+	{"TiB", 1 << 40}, {"TB", 1e12},
+	{"GiB", 1 << 30}, {"GB", 1e9},
+	{"MiB", 1 << 20}, {"MB", 1e6},
+	{"KiB", 1 << 10}, {"KB", 1e3},
+	{"B", 1},
+}
+
+var rateIntervals = []struct {
+	name     string
+	interval time.Duration
+}{
+	{"ns", time.Nanosecond}, {"us", time.Microsecond}, {"ms", time.Millisecond},
+	{"s", time.Second}, {"sec", time.Second},
+	{"min", time.Minute}, {"m", time.Minute},
+	{"h", time.Hour}, {"hour", time.Hour},
+	{"d", 24 * time.Hour}, {"day", 24 * time.Hour},
+}
+
+var throttleModes = map[string]gu_time.ThrottleType{
+	"avg":     gu_time.ThrottleAverageValue,
+	"instant": gu_time.ThrottleInstantValue,
+	"both":    gu_time.ThrottleBoth,
+}
+
+// Rate is Amount Units per Interval, or a duty cycle when Duty is set,
+// with Amount between 0 and 1; Scale is the size of a unit in bytes for
+// the size units, and 1 for any other unit. A zero Amount means no limit
+type Rate struct {
+	Amount   float64
+	Units    string
+	Scale    float64
+	Interval time.Duration
+	Mode     gu_time.ThrottleType
+	Duty     bool
+}
+
+// splitNumber splits s after its leading decimal number
+func splitNumber(s string) (float64, string, error) {
+	i := 0
+	for i < len(s) && (s[i] == '.' || (s[i] >= '0' && s[i] <= '9')) {
+		i++
+	}
+	x, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", numberError(err)
+	}
+	return x, s[i:], nil
+}
+
+func lookupSizeUnit(name string) (string, int64, bool) {
+	for _, u := range sizeUnits {
+		if strings.EqualFold(u.name, name) {
+			return u.name, u.size, true
+		}
+	}
+	return "", 0, false
+}
+
+// ParseSize parses a number of bytes, optionally followed by a decimal
+// (KB, MB, ...) or binary (KiB, MiB, ...) unit, ignoring case
+func ParseSize(s string) (int64, error) {
+	x, unit, err := splitNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(1)
+	if len(unit) > 0 {
+		var known bool
+		if _, size, known = lookupSizeUnit(unit); !known {
+			return 0, errors.New(fmt.Sprintf("unknown size unit %q", unit))
+		}
+	}
+	x = math.Round(x * float64(size))
+	if x >= math.MaxInt64 {
+		return 0, OUT_OF_RANGE
+	}
+	return int64(x), nil
+}
+
+// FormatSize formats n with the largest unit dividing it
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits {
+		if n != 0 && n%u.size == 0 {
+			return fmt.Sprintf("%d%s", n/u.size, u.name)
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func formatAmount(x float64) string {
+	return strconv.FormatFloat(x, 'g', 12, 64)
+}
+
+func intervalName(d time.Duration) string {
+	for _, i := range rateIntervals {
+		if i.interval == d {
+			return i.name
+		}
+	}
+	return d.String()
+}
+
+// ParseRate parses an amount with optional units per time unit, like
+// 10MB/s, 300req/min or 5/100ms, optionally followed by :avg, :instant
+// or :both, or a duty cycle like 50%duty; "0" means no limit. The mode
+// defaults to average
+func ParseRate(s string) (Rate, error) {
+	r := Rate{Scale: 1, Interval: time.Second, Mode: gu_time.ThrottleAverageValue}
+	if i := strings.LastIndex(s, RATE_MODE_SEP); i >= 0 {
+		mode, known := throttleModes[s[i+1:]]
+		if !known {
+			return Rate{}, UNKNOWN_MODE
+		}
+		s, r.Mode = s[:i], mode
+	}
+	if strings.HasSuffix(s, RATE_DUTY_SUFFIX) {
+		x, rest, err := splitNumber(strings.TrimSuffix(s, RATE_DUTY_SUFFIX))
+		switch {
+		case err != nil:
+			return Rate{}, err
+		case len(rest) > 0:
+			return Rate{}, BAD_RATE
+		case x > 100:
+			return Rate{}, OUT_OF_RANGE
+		}
+		r.Amount, r.Duty = x/100, true
+		return r, nil
+	}
+	if s == "0" {
+		return r, nil
+	}
+	i := strings.LastIndex(s, "/")
+	if i < 0 {
+		return Rate{}, MISSING_INTERVAL
+	}
+	amount, per := s[:i], s[i+1:]
+	x, units, err := splitNumber(amount)
+	if err != nil {
+		return Rate{}, err
+	}
+	r.Amount, r.Units = x, units
+	if name, size, known := lookupSizeUnit(units); known {
+		r.Units, r.Scale = name, float64(size)
+	}
+	for _, i := range rateIntervals {
+		if i.name == per {
+			r.Interval = i.interval
+			return r, nil
+		}
+	}
+	if r.Interval, err = time.ParseDuration(per); err != nil || r.Interval <= 0 {
+		return Rate{}, errors.New(fmt.Sprintf("unknown time unit %q", per))
+	}
+	return r, nil
+}
+
+// SpeedName names the units of the rate, as in MB/s
+func (r Rate) SpeedName() string {
+	return r.Units + "/" + intervalName(r.Interval)
+}
+
+func (r Rate) String() string {
+	if r.Duty {
+		return formatAmount(r.Amount*100) + RATE_DUTY_SUFFIX
+	}
+	if r.Amount == 0 && len(r.Units) == 0 {
+		return "0"
+	}
+	s := formatAmount(r.Amount) + r.SpeedName()
+	for name, mode := range throttleModes {
+		if mode == r.Mode && mode != gu_time.ThrottleAverageValue {
+			s += RATE_MODE_SEP + name
+		}
+	}
+	return s
+}
+
+// Apply sets the duty cycle of t, for a duty cycle, or the maximum rate,
+// the mode and the units of its named quantity, defining it if needed;
+// an existing quantity must implement gu_time.RateSetter, and the panics
+// of a started or frozen throttler are returned as errors
+func (r Rate) Apply(t gu_time.Throttler, quantity string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = errors.New(fmt.Sprint(p))
+		}
+	}()
+	if r.Duty {
+		t.SetDutyCycle(r.Amount)
+		return nil
+	}
+	tq := t.GetThrottledQuantity(quantity)
+	if tq == nil {
+		tq = t.DefineThrottledQuantity(quantity, r.Amount, r.Mode)
+	} else if rs, ok := tq.(gu_time.RateSetter); ok {
+		rs.SetMaxRate(r.Amount, r.Mode)
+	} else {
+		return FIXED_RATE
+	}
+	tq.SetUnits(r.Units, r.SpeedName(), r.Scale, r.Interval)
+	return nil
+}
+
+type sizeValue struct {
+	target  *int64
+	initial int64
+	factory func(*FlagSet, string)
+}
+
+func (sv *sizeValue) Set(v string) error {
+	n, err := ParseSize(v)
+	if err == nil {
+		*sv.target = n
+	}
+	return err
+}
+
+func (sv *sizeValue) String() string {
+	if sv == nil || sv.target == nil {
+		return ""
+	}
+	return FormatSize(*sv.target)
+}
+
+func (sv *sizeValue) Get() interface{} {
+	return *sv.target
+}
+
+func (sv *sizeValue) restoreDefault() {
+	*sv.target = sv.initial
+}
+
+func (sv *sizeValue) redefine(c *FlagSet, name string) {
+	sv.factory(c, name)
+}
+
+type rateValue struct {
+	target  *Rate
+	initial Rate
+	apply   func(Rate) error
+	applied *Rate
+	factory func(*FlagSet, string)
+}
+
+// Set only stores the rate: settleRates applies it once the whole parse
+// succeeded
+func (rv *rateValue) Set(v string) error {
+	r, err := ParseRate(v)
+	if err == nil {
+		*rv.target = r
+	}
+	return err
+}
+
+func (rv *rateValue) String() string {
+	if rv == nil || rv.target == nil {
+		return ""
+	}
+	return rv.target.String()
+}
+
+func (rv *rateValue) Get() interface{} {
+	return *rv.target
+}
+
+func (rv *rateValue) restoreDefault() {
+	*rv.target = rv.initial
+}
+
+// settle applies the current value, if it is not the last one applied
+func (rv *rateValue) settle() error {
+	if rv.apply == nil || *rv.target == *rv.applied {
+		return nil
+	}
+	err := rv.apply(*rv.target)
+	if err == nil {
+		*rv.applied = *rv.target
+	}
+	return err
+}
+
+func (rv *rateValue) redefine(c *FlagSet, name string) {
+	rv.factory(c, name)
+}
+
+func (f *FlagSet) Size(name string, value int64, usage string) *int64 {
+	p := new(int64)
+	f.SizeVar(p, name, value, usage)
+	return p
+}
+
+func (f *FlagSet) SizeVar(p *int64, name string, value int64, usage string) {
+	sv := &sizeValue{target: p, initial: value}
+	sv.factory = func(c *FlagSet, name string) {
+		c.SizeVar(new(int64), name, value, usage)
+	}
+	*p = value
+	f.Flags.Var(sv, name, usage)
+}
+
+func (f *FlagSet) Rate(name string, value string, usage string) *Rate {
+	p := new(Rate)
+	f.RateVar(p, name, value, usage)
+	return p
+}
+
+// RateVar defines a rate flag; value is its default, and must be a
+// valid rate
+func (f *FlagSet) RateVar(p *Rate, name string, value string, usage string) {
+	f.rateVar(p, name, value, usage, nil, nil)
+}
+
+// RateLimit defines a rate flag applied to the named quantity of t, see
+// Rate.Apply: after every successful parse t gets the value of the flag,
+// or its default when not set, if it is not the one last given; clones
+// of this FlagSet update the same throttler
+func (f *FlagSet) RateLimit(t gu_time.Throttler, quantity string, name string, value string, usage string) *Rate {
+	p := new(Rate)
+	f.rateVar(p, name, value, usage, func(r Rate) error {
+		return r.Apply(t, quantity)
+	}, nil)
+	return p
+}
+
+// rateVar defines a rate flag; applied is the last rate given to apply,
+// shared by clones, and a nil one starts as the zero Rate, which no
+// parse gives, so that the first settle applies the value anyway
+func (f *FlagSet) rateVar(p *Rate, name string, value string, usage string, apply func(Rate) error, applied *Rate) {
+	r, err := ParseRate(value)
+	if apply != nil && applied == nil {
+		applied = new(Rate)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("bad default rate %q for -%s: %v", value, name, err))
+	}
+	rv := &rateValue{target: p, initial: r, apply: apply, applied: applied}
+	rv.factory = func(c *FlagSet, name string) {
+		c.rateVar(new(Rate), name, value, usage, apply, applied)
+	}
+	*p = r
+	f.Flags.Var(rv, name, usage)
+}
+
+// settleRates gives the throttlers the values of the rate flags after a
+// successful parse, defaults included
+func (f *FlagSet) settleRates() error {
+	var err error
+	f.Walk(func(s *FlagSet) {
+		s.Flags.VisitAll(func(fl *flag.Flag) {
+			if rv, ok := fl.Value.(*rateValue); ok && err == nil {
+				if serr := rv.settle(); serr != nil {
+					pe := s.parseError(BadFlagValue, "-"+fl.Name, serr)
+					pe.Flag, pe.Value = fl.Name, rv.String()
+					err = pe
+				}
+			}
+		})
+	})
+	return err
+}
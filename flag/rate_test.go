@@ -0,0 +1,134 @@
+package gu_flag
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maxcalandrelli/goutil/time"
+)
+
+func TestSizes(t *testing.T) {
+	cases := []struct {
+		s    string
+		n    int64
+		text string
+		err  bool
+	}{
+		{"0", 0, "0", false},
+		{"1500", 1500, "1500B", false},
+		{"64KiB", 64 << 10, "64KiB", false},
+		{"1.5GB", 1500000000, "1500MB", false},
+		{"2mib", 2 << 20, "2MiB", false},
+		{"3XB", 0, "", true},
+		{"MB", 0, "", true},
+		{"10000PiB", 0, "", true},
+	}
+	for _, c := range cases {
+		n, err := ParseSize(c.s)
+		if (err != nil) != c.err || n != c.n {
+			t.Errorf("%s: got %d, %v", c.s, n, err)
+			continue
+		}
+		if !c.err && FormatSize(n) != c.text {
+			t.Errorf("%s: formatted as %s", c.s, FormatSize(n))
+		}
+	}
+}
+
+func TestRates(t *testing.T) {
+	cases := []struct {
+		s    string
+		rate Rate
+		err  error
+	}{
+		{"0", Rate{Scale: 1, Interval: time.Second, Mode: gu_time.ThrottleAverageValue}, nil},
+		{"10MB/s", Rate{Amount: 10, Units: "MB", Scale: 1e6, Interval: time.Second, Mode: gu_time.ThrottleAverageValue}, nil},
+		{"300req/min:both", Rate{Amount: 300, Units: "req", Scale: 1, Interval: time.Minute, Mode: gu_time.ThrottleBoth}, nil},
+		{"5/100ms:instant", Rate{Amount: 5, Scale: 1, Interval: 100 * time.Millisecond, Mode: gu_time.ThrottleInstantValue}, nil},
+		{"50%duty", Rate{Amount: 0.5, Scale: 1, Interval: time.Second, Mode: gu_time.ThrottleAverageValue, Duty: true}, nil},
+		{"10MB", Rate{}, MISSING_INTERVAL},
+		{"10MB/s:fast", Rate{}, UNKNOWN_MODE},
+		{"150%duty", Rate{}, OUT_OF_RANGE},
+	}
+	for _, c := range cases {
+		r, err := ParseRate(c.s)
+		if err != c.err || r != c.rate {
+			t.Errorf("%s: got %+v, %v", c.s, r, err)
+			continue
+		}
+		if err == nil {
+			if again, _ := ParseRate(r.String()); again != r {
+				t.Errorf("%s: %s parsed as %+v", c.s, r, again)
+			}
+		}
+	}
+}
+
+func TestSizeAndRateFlags(t *testing.T) {
+	f := NewRoot("prog", "")
+	chunk := f.Size("chunk", 4<<10, "")
+	bw := f.Rate("bw", "1MB/s", "")
+	if err := f.Parse([]string{"-chunk=1MiB", "-bw=2KB/s"}); err != nil {
+		t.Fatal(err)
+	}
+	if *chunk != 1<<20 || bw.Amount != 2 || bw.Units != "KB" {
+		t.Errorf("got %d %+v", *chunk, *bw)
+	}
+	f.Reset()
+	if err := f.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if *chunk != 4<<10 || bw.String() != "1MB/s" {
+		t.Errorf("defaults not restored: %d %s", *chunk, bw)
+	}
+	var pe *ParseError
+	if err := f.Parse([]string{"-bw=fast"}); !errors.As(err, &pe) || pe.Flag != "bw" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	th := gu_time.GlobalThrottler.NewThrottler("rate-test")
+	f := NewRoot("prog", "")
+	f.RateLimit(th, "bytes", "bw", "1MB/s", "")
+	f.Flags.Int("n", 0, "")
+	if th.GetThrottledQuantity("bytes") != nil {
+		t.Fatalf("throttler changed by the definition")
+	}
+	if err := f.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	tq := th.GetThrottledQuantity("bytes")
+	if tq == nil || tq.GetMaxRate() != 1 || tq.GetUnits() != 1e6 || tq.GetSpeedUnitsName() != "MB/s" {
+		t.Fatalf("default not applied: %v", tq)
+	}
+	if err := f.Parse([]string{"-bw=2KB/s:instant"}); err != nil {
+		t.Fatal(err)
+	}
+	if tq.GetMaxRate() != 2 || tq.GetUnits() != 1e3 || tq.GetThrottleType() != gu_time.ThrottleInstantValue {
+		t.Errorf("value not applied: %v %v %v", tq.GetMaxRate(), tq.GetUnits(), tq.GetThrottleType())
+	}
+	if err := f.Parse([]string{"-bw=99MB/s", "-n=notanumber"}); err == nil || tq.GetMaxRate() != 2 {
+		t.Errorf("failed parse applied: %v %v", err, tq.GetMaxRate())
+	}
+	c := f.Clone()
+	if tq.GetMaxRate() != 2 {
+		t.Errorf("clone applied its default")
+	}
+	if err := c.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if tq.GetMaxRate() != 1 || tq.GetThrottleType() != gu_time.ThrottleAverageValue {
+		t.Errorf("default not applied again: %v", tq.GetMaxRate())
+	}
+	th.StartOperation()
+	var pe *ParseError
+	if err := f.Parse([]string{"-bw=3MB/s"}); !errors.As(err, &pe) || pe.Flag != "bw" {
+		t.Errorf("got %v", err)
+	}
+	th.StopOperation()
+	if tq.GetMaxRate() != 1 {
+		t.Errorf("rate changed on a started throttler: %v", tq.GetMaxRate())
+	}
+}
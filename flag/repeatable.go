@@ -25,6 +25,8 @@ type RepeatableArg interface {
 	GetValues() []string
 	String() string
 	reset()
+	restoreDefault()
+	clone(*FlagSet, string) RepeatableArg
 }
 
 type listOrSetArg struct {
@@ -34,11 +36,19 @@ type listOrSetArg struct {
 	canonicalizer func(string) string
 	inserter      func(string) error
 	values        *[]string
+	defaults      []string
+	factory       func(*FlagSet, string) RepeatableArg
 }
 
 type setArg struct {
 	listOrSetArg
-	values_present map[string]bool
+	values_present   map[string]bool
+	allowed          []string
+	ignoreDuplicates bool
+	wildcards        bool
+	elementOps       bool
+	op               byte
+	rejected         string
 }
 
 func (losa *listOrSetArg) String() string {
@@ -92,13 +102,6 @@ func toString(m map[string]bool) string {
 	return strings.Join(r, ",")
 }
 
-func (sa *setArg) Set(v string) error {
-	if sa.IsDefault() {
-		sa.values_present = map[string]bool{}
-	}
-	return sa.listOrSetArg.Set(v)
-}
-
 func (losa *listOrSetArg) Set(v string) error {
 	if losa.IsDefault() {
 		losa.reset()
@@ -112,6 +115,12 @@ func (losa *listOrSetArg) Set(v string) error {
 	return nil
 }
 
+// AllowedValues returns the values accepted by a constrained set, or nil
+// if any value is accepted
+func (sa *setArg) AllowedValues() []string {
+	return sa.allowed
+}
+
 func (losa *listOrSetArg) GetValues() []string {
 	if losa.values == nil {
 		return []string{}
@@ -123,6 +132,23 @@ func (losa *listOrSetArg) reset() {
 	*(losa.values) = []string{}
 }
 
+// restoreDefault brings back the values the arg was defined with,
+// bypassing the inserter as the definition did; a nil slice stays nil
+func (losa *listOrSetArg) restoreDefault() {
+	*(losa.values) = nil
+	if losa.defaults != nil {
+		*(losa.values) = append([]string{}, losa.defaults...)
+	}
+	losa.setDefault(true)
+}
+
+// clone defines a flag like this one, with fresh storage, in c
+func (losa *listOrSetArg) clone(c *FlagSet, name string) RepeatableArg {
+	r := losa.factory(c, name)
+	r.SetSeparator(losa.separator)
+	return r
+}
+
 func newListArg(values *[]string, separator string, default_value []string, canonicalizer func(string) string, inserter func(string) error) *listOrSetArg {
 	la := listOrSetArg{
 		values:        values,
@@ -133,6 +159,9 @@ func newListArg(values *[]string, separator string, default_value []string, cano
 	for _, _v := range default_value {
 		la.getInserter()(_v)
 	}
+	if values != nil && *values != nil {
+		la.defaults = append([]string{}, *values...)
+	}
 	la.setDefault(true)
 	return &la
 }
@@ -142,12 +171,21 @@ func (sa *setArg) reset() {
 	sa.listOrSetArg.reset()
 }
 
+func (sa *setArg) restoreDefault() {
+	sa.listOrSetArg.restoreDefault()
+	sa.values_present = map[string]bool{}
+	for _, v := range sa.defaults {
+		sa.values_present[v] = true
+	}
+}
+
 func (f *FlagSet) List(name string, value []string, usage string) RepeatableArg {
 	return f.ListVar(new([]string), name, value, usage)
 }
 
 func (f *FlagSet) ListVar(p *[]string, name string, value []string, usage string) RepeatableArg {
 	values := newListArg(p, ",", value, func(v string) string { return v }, func(v string) error { *p = append(*p, v); return nil })
+	values.factory = func(c *FlagSet, name string) RepeatableArg { return c.ListVar(new([]string), name, value, usage) }
 	f.Flags.Var(values, name, usage)
 	return values
 }
@@ -156,8 +194,8 @@ func (f *FlagSet) Set(name string, value []string, usage string, ignoreCase bool
 	return f.SetVar(new([]string), name, value, usage, ignoreCase)
 }
 
-func (f *FlagSet) SetVar(p *[]string, name string, value []string, usage string, ignoreCase bool) RepeatableArg {
-	sa := setArg{}
+func newSetArg(p *[]string, value []string, ignoreCase bool) *setArg {
+	sa := &setArg{}
 	sa.values_present = map[string]bool{}
 	sa.listOrSetArg = *newListArg(
 		p,
@@ -182,8 +220,16 @@ func (f *FlagSet) SetVar(p *[]string, name string, value []string, usage string,
 		sa.Set(_v)
 	}
 	sa.setDefault(true)
-	f.Flags.Var(&sa, name, usage)
-	return &sa
+	return sa
+}
+
+func (f *FlagSet) SetVar(p *[]string, name string, value []string, usage string, ignoreCase bool) RepeatableArg {
+	sa := newSetArg(p, value, ignoreCase)
+	sa.factory = func(c *FlagSet, name string) RepeatableArg {
+		return c.SetVar(new([]string), name, value, usage, ignoreCase)
+	}
+	f.Flags.Var(sa, name, usage)
+	return sa
 }
 
 func (f *FlagSet) ConstrainedSet(name string, value []string, allowed []string, usage string, ignoreCase bool) RepeatableArg {
@@ -196,6 +242,10 @@ func (f *FlagSet) ConstrainedSetVar(p *[]string, name string, value []string, al
 	for _, _v := range allowed {
 		validMap[sa.getCanonicalizer()(_v)] = true
 	}
+	sa.(*setArg).allowed = allowed
+	sa.(*setArg).factory = func(c *FlagSet, name string) RepeatableArg {
+		return c.ConstrainedSetVar(new([]string), name, value, allowed, usage, ignoreCase)
+	}
 	_i := sa.getInserter()
 	sa.setInserter(func(v string) error {
 		if _, _valid := validMap[v]; _valid {
@@ -0,0 +1,148 @@
+// With ResponseFiles enabled, an @path argument is replaced by the words
+// read from the file at path, split as by SplitCommandLine one line at a
+// time; response files can include other ones, with relative paths
+// resolved from the including file. A @@ argument stands for a literal
+// @, and no expansion happens after --
+package gu_flag
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	RESPONSE_FILE_PREFIX = "@"
+)
+
+var (
+	RESPONSE_FILE_CYCLE error = errors.New("response file includes itself")
+)
+
+type ResponseFileError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (rfe *ResponseFileError) Error() string {
+	if len(rfe.File) == 0 {
+		return fmt.Sprintf("response file: %v", rfe.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", rfe.File, rfe.Line, rfe.Err)
+}
+
+func (rfe *ResponseFileError) Unwrap() error {
+	return rfe.Err
+}
+
+// argPosition is where an arg comes from: an empty file stands for the
+// command line itself
+type argPosition struct {
+	file string
+	line int
+}
+
+func (f *FlagSet) responseFiles() bool {
+	for s := f; s != nil; s = s.parent {
+		if s.ResponseFiles {
+			return true
+		}
+	}
+	return false
+}
+
+func expandResponseFiles(args []string) ([]string, []argPosition, error) {
+	r := []string{}
+	positions := []argPosition{}
+	for i, a := range args {
+		if a == "--" {
+			for _, rest := range args[i:] {
+				r = append(r, rest)
+				positions = append(positions, argPosition{})
+			}
+			break
+		}
+		if err := expandArg(a, argPosition{}, "", map[string]bool{}, &r, &positions); err != nil {
+			return nil, nil, err
+		}
+	}
+	return r, positions, nil
+}
+
+// expandArg appends to r either a or the content of the response file it
+// names, resolved from dir; open lists the files being read, to detect
+// cycles
+func expandArg(a string, at argPosition, dir string, open map[string]bool, r *[]string, positions *[]argPosition) error {
+	if !strings.HasPrefix(a, RESPONSE_FILE_PREFIX) || len(a) == 1 {
+		*r = append(*r, a)
+		*positions = append(*positions, at)
+		return nil
+	}
+	if strings.HasPrefix(a, RESPONSE_FILE_PREFIX+RESPONSE_FILE_PREFIX) {
+		*r = append(*r, a[1:])
+		*positions = append(*positions, at)
+		return nil
+	}
+	path := a[1:]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return &ResponseFileError{File: at.file, Line: at.line, Err: err}
+	}
+	if open[abs] {
+		return &ResponseFileError{File: at.file, Line: at.line, Err: RESPONSE_FILE_CYCLE}
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return &ResponseFileError{File: at.file, Line: at.line, Err: err}
+	}
+	defer in.Close()
+	open[abs] = true
+	defer delete(open, abs)
+	scanner := bufio.NewScanner(in)
+	for n := 1; scanner.Scan(); n++ {
+		here := argPosition{file: path, line: n}
+		words, err := SplitCommandLine(scanner.Text())
+		if err != nil {
+			return &ResponseFileError{File: path, Line: n, Err: err}
+		}
+		for _, w := range words {
+			if err := expandArg(w, here, filepath.Dir(path), open, r, positions); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &ResponseFileError{File: path, Err: err}
+	}
+	return nil
+}
+
+// locateError sets the position of the offending token of a parse error
+// coming from a response file: it is found counting the args left when
+// known, and looking for the first arg matching otherwise
+func locateError(err error, args []string, positions []argPosition) {
+	pe, ok := err.(*ParseError)
+	if !ok || len(positions) == 0 {
+		return
+	}
+	i := len(args) - pe.argsLeft
+	if pe.argsLeft == 0 {
+		i = -1
+		for j, a := range args {
+			if (len(pe.Token) > 0 && a == pe.Token) || (len(pe.Value) > 0 && a == pe.Value) {
+				i = j
+				break
+			}
+		}
+	}
+	if i >= 0 {
+		pe.File, pe.Line = positions[i].file, positions[i].line
+	}
+}
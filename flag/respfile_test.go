@@ -0,0 +1,71 @@
+package gu_flag
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeResponseFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func respFileTree() (*FlagSet, *string) {
+	root := NewRoot("prog", "")
+	root.ResponseFiles = true
+	name := root.Flags.String("name", "", "")
+	root.Flags.Int("count", 0, "")
+	root.NewFlagSet("run", "").NonKeywordArgs = true
+	return root, name
+}
+
+func TestResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeResponseFile(t, filepath.Join(dir, "main.rsp"), "-name='big hat'\n@sub/more.rsp\n")
+	writeResponseFile(t, filepath.Join(dir, "sub", "more.rsp"), "-count=3 run\n@@literal\n")
+	root, name := respFileTree()
+	if err := root.Parse([]string{"@" + filepath.Join(dir, "main.rsp"), "x", "--", "@main.rsp"}); err != nil {
+		t.Fatal(err)
+	}
+	run := root.subSets["run"]
+	if *name != "big hat" || root.Value("count") != 3 || strings.Join(run.Args(), "|") != "@literal|x|--|@main.rsp" {
+		t.Errorf("got %q %v %q", *name, root.Value("count"), run.Args())
+	}
+	root.ResponseFiles = false
+	if err := root.Parse([]string{"run", "@" + filepath.Join(dir, "main.rsp")}); err != nil || run.Args()[0][0] != '@' {
+		t.Errorf("expanded with ResponseFiles off: %v %q", err, run.Args())
+	}
+}
+
+func TestResponseFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	loop := filepath.Join(dir, "loop.rsp")
+	bad := filepath.Join(dir, "bad.rsp")
+	quote := filepath.Join(dir, "quote.rsp")
+	writeResponseFile(t, loop, "-name=x\n@loop.rsp\n")
+	writeResponseFile(t, bad, "-name=x\n\n-count=many run\n")
+	writeResponseFile(t, quote, "-name='open\n")
+	var rfe *ResponseFileError
+	root, _ := respFileTree()
+	if err := root.Parse([]string{"@" + loop}); !errors.As(err, &rfe) || rfe.Err != RESPONSE_FILE_CYCLE || rfe.File != loop || rfe.Line != 2 {
+		t.Errorf("cycle: got %v", err)
+	}
+	if err := root.Parse([]string{"@" + filepath.Join(dir, "missing.rsp")}); !errors.As(err, &rfe) || !errors.Is(err, os.ErrNotExist) || rfe.File != "" {
+		t.Errorf("missing file: got %v", err)
+	}
+	if err := root.Parse([]string{"@" + quote}); !errors.As(err, &rfe) || rfe.File != quote || rfe.Line != 1 {
+		t.Errorf("unterminated quote: got %v", err)
+	}
+	var pe *ParseError
+	if err := root.Parse([]string{"@" + bad}); !errors.As(err, &pe) || pe.Kind != BadFlagValue || pe.File != bad || pe.Line != 3 {
+		t.Errorf("bad value: got %#v", err)
+	}
+}
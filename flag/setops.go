@@ -0,0 +1,187 @@
+// Set values can be edited instead of replaced: -heels+=extreme adds
+// extreme to the set, keeping the default values, and -heels-=high
+// removes high. Sets enabled by ElementOperators also accept an operator
+// before each element, as in -heels=+extreme,-high; elsewhere a leading
+// + or - is part of the value
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	SET_ADD    = '+'
+	SET_REMOVE = '-'
+)
+
+func (sa *setArg) Set(v string) error {
+	for _, _v := range strings.Split(v, sa.GetSeparator()) {
+		op := sa.op
+		if op == 0 && sa.elementOps && len(_v) > 0 && (_v[0] == SET_ADD || _v[0] == SET_REMOVE) {
+			op, _v = _v[0], _v[1:]
+		}
+		if sa.IsDefault() {
+			if op == 0 {
+				sa.reset()
+			}
+			sa.setDefault(false)
+		}
+		if err := sa.apply(op, _v); err != nil {
+			sa.rejected = _v
+			return err
+		}
+	}
+	return nil
+}
+
+// apply adds or removes a single element, or all the allowed values
+// matching it when it is a wildcard
+func (sa *setArg) apply(op byte, v string) error {
+	v = sa.canonicalizer(v)
+	elements := []string{v}
+	expanded := false
+	if sa.wildcards && strings.ContainsAny(v, "*?[") {
+		elements = []string{}
+		for _, a := range sa.allowed {
+			a = sa.canonicalizer(a)
+			if matched, err := path.Match(v, a); err != nil {
+				return err
+			} else if matched {
+				elements = append(elements, a)
+			}
+		}
+		if len(elements) == 0 {
+			return NOT_IN_SET
+		}
+		expanded = true
+	}
+	for _, e := range elements {
+		if op == SET_REMOVE {
+			if err := sa.remove(e); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := sa.inserter(e); err == DUPLICATE_VALUE && (expanded || sa.ignoreDuplicates) {
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sa *setArg) clone(c *FlagSet, name string) RepeatableArg {
+	r := sa.listOrSetArg.clone(c, name)
+	r.(*setArg).ignoreDuplicates = sa.ignoreDuplicates
+	r.(*setArg).wildcards = sa.wildcards
+	r.(*setArg).elementOps = sa.elementOps
+	return r
+}
+
+func (sa *setArg) isAllowed(v string) bool {
+	if sa.allowed == nil {
+		return true
+	}
+	for _, a := range sa.allowed {
+		if sa.canonicalizer(a) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// remove takes v out of the set; removing a value not present is not an
+// error, unless the value could never be there
+func (sa *setArg) remove(v string) error {
+	if !sa.isAllowed(v) {
+		return NOT_IN_SET
+	}
+	if !sa.values_present[v] {
+		return nil
+	}
+	delete(sa.values_present, v)
+	r := []string{}
+	for _, _v := range *(sa.values) {
+		if _v != v {
+			r = append(r, _v)
+		}
+	}
+	*(sa.values) = r
+	return nil
+}
+
+func (f *FlagSet) setFlags(names []string) ([]*setArg, error) {
+	r := []*setArg{}
+	for _, n := range names {
+		fl := f.Flags.Lookup(n)
+		if fl == nil {
+			return nil, errors.New(fmt.Sprintf("no such flag -%s", n))
+		}
+		sa, ok := fl.Value.(*setArg)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("flag -%s is not a set", n))
+		}
+		r = append(r, sa)
+	}
+	return r, nil
+}
+
+// IgnoreDuplicates makes the named set flags accept values already
+// present, instead of failing with DUPLICATE_VALUE
+func (f *FlagSet) IgnoreDuplicates(names ...string) error {
+	sets, err := f.setFlags(names)
+	for _, sa := range sets {
+		sa.ignoreDuplicates = true
+	}
+	return err
+}
+
+// ElementOperators makes the named set flags read a leading + or - of
+// each element as an operator, adding or removing that element
+func (f *FlagSet) ElementOperators(names ...string) error {
+	sets, err := f.setFlags(names)
+	for _, sa := range sets {
+		sa.elementOps = true
+	}
+	return err
+}
+
+// MatchWildcards makes the named constrained set flags accept shell
+// patterns, as in -heels=*high, standing for all the allowed values
+// they match
+func (f *FlagSet) MatchWildcards(names ...string) error {
+	sets, err := f.setFlags(names)
+	for _, sa := range sets {
+		if sa.allowed == nil {
+			return errors.New("wildcards need a constrained set")
+		}
+	}
+	for _, sa := range sets {
+		sa.wildcards = true
+	}
+	return err
+}
+
+// setOperator splits -name+=a,b and -name-=a,b into the name of a set
+// flag and the operator to apply to all the elements given
+func (f *FlagSet) setOperator(name string) (string, byte) {
+	n := len(name)
+	if n < 2 || (name[n-1] != SET_ADD && name[n-1] != SET_REMOVE) {
+		return name, 0
+	}
+	if fl, _ := f.lookup(name); fl != nil {
+		return name, 0
+	}
+	fl, _ := f.lookup(name[:n-1])
+	if fl == nil {
+		return name, 0
+	}
+	if _, ok := fl.Value.(*setArg); !ok {
+		return name, 0
+	}
+	return name[:n-1], name[n-1]
+}
@@ -0,0 +1,93 @@
+package gu_flag
+
+import (
+	"strings"
+	"testing"
+)
+
+func setopsTree(t *testing.T, enable func(f *FlagSet) error) (*FlagSet, *[]string, *[]string) {
+	t.Helper()
+	f := NewRoot("prog", "")
+	leather := new([]string)
+	heels := new([]string)
+	f.SetVar(leather, "leather", []string{"cow"}, "", true)
+	f.ConstrainedSetVar(heels, "heels", []string{"high"}, []string{"low", "high", "veryhigh", "extreme"}, "", true)
+	if enable != nil {
+		if err := enable(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f, leather, heels
+}
+
+func TestSetOperators(t *testing.T) {
+	cases := []struct {
+		args    string
+		leather string
+		heels   string
+	}{
+		{"", "cow", "high"},
+		{"-leather=pig", "pig", "high"},
+		{"-leather+=pig,goat", "cow,pig,goat", "high"},
+		{"-leather-=cow", "", "high"},
+		{"-leather=-cow,+pig", "-cow,+pig", "high"},
+		{"-heels+=extreme -heels-=high", "cow", "extreme"},
+		{"-leather=a -leather+=b", "a,b", "high"},
+	}
+	for _, c := range cases {
+		f, leather, heels := setopsTree(t, nil)
+		if err := f.Parse(strings.Fields(c.args)); err != nil {
+			t.Errorf("%q: %v", c.args, err)
+			continue
+		}
+		if strings.Join(*leather, ",") != c.leather || strings.Join(*heels, ",") != c.heels {
+			t.Errorf("%q: got %v %v", c.args, *leather, *heels)
+		}
+	}
+}
+
+func TestElementOperators(t *testing.T) {
+	cases := []struct {
+		args  string
+		heels string
+	}{
+		{"-heels=+extreme,-high", "extreme"},
+		{"-heels=+low", "high,low"},
+		{"-heels=low,+extreme", "low,extreme"},
+	}
+	for _, c := range cases {
+		f, leather, heels := setopsTree(t, func(f *FlagSet) error { return f.ElementOperators("heels") })
+		if err := f.Parse(append(strings.Fields(c.args), "-leather=-cow")); err != nil {
+			t.Errorf("%q: %v", c.args, err)
+			continue
+		}
+		if strings.Join(*heels, ",") != c.heels || strings.Join(*leather, ",") != "-cow" {
+			t.Errorf("%q: got %v %v", c.args, *heels, *leather)
+		}
+	}
+	f, _, _ := setopsTree(t, nil)
+	if err := f.ElementOperators("nosuch"); err == nil {
+		t.Errorf("enabled on an undefined flag")
+	}
+}
+
+func TestSetWildcardsAndDuplicates(t *testing.T) {
+	f, leather, heels := setopsTree(t, func(f *FlagSet) error {
+		if err := f.IgnoreDuplicates("leather"); err != nil {
+			return err
+		}
+		return f.MatchWildcards("heels")
+	})
+	if err := f.Parse([]string{"-heels=*high", "-leather=pig,pig"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(*heels, ",") != "high,veryhigh" || strings.Join(*leather, ",") != "pig" {
+		t.Errorf("got %v %v", *heels, *leather)
+	}
+	if err := f.Parse([]string{"-heels=*flat"}); err == nil {
+		t.Errorf("unmatched wildcard accepted")
+	}
+	if err := f.MatchWildcards("leather"); err == nil {
+		t.Errorf("wildcards enabled on an unconstrained set")
+	}
+}
@@ -0,0 +1,251 @@
+// A Shell reads command lines from a reader and dispatches each of them
+// through a FlagSet tree, as if given to the program. Every command is
+// parsed on the tree itself, reset to the default values before each
+// line, so that hooks find the values of the line in the variables bound
+// to the flags as well; the parse state of the command running the shell
+// (the selected keywords, the args, the context and the origins) is
+// brought back when the shell ends, while the values are left as the
+// last line set them. Besides
+// the keywords of the tree, the shell knows the exit, quit and history
+// commands, and a line ending with a tab lists the completions of its
+// last word
+package gu_flag
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	SHELL_KEYWORD = "shell"
+)
+
+var (
+	UNTERMINATED_QUOTE  error = errors.New("unterminated quote")
+	UNTERMINATED_ESCAPE error = errors.New("escape at end of line")
+	ALREADY_IN_SHELL    error = errors.New("shell already running")
+)
+
+type Shell struct {
+	Prompt  string
+	root    *FlagSet
+	in      io.Reader
+	out     io.Writer
+	history []string
+	running bool
+}
+
+// SplitCommandLine splits line into words the way a POSIX shell does,
+// without any expansion: single quotes keep their content as it is,
+// double quotes allow escaping \, " and $ with a backslash, and an
+// unquoted # starting a word opens a comment
+func SplitCommandLine(line string) ([]string, error) {
+	r := []string{}
+	word := []rune{}
+	inWord := false
+	quote := rune(0)
+	escaped := false
+	for _, c := range line {
+		switch {
+		case escaped:
+			if quote == '"' && c != '"' && c != '\\' && c != '$' && c != '`' {
+				word = append(word, '\\')
+			}
+			word = append(word, c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			word = append(word, c)
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				r = append(r, string(word))
+				word, inWord = []rune{}, false
+			}
+		case c == '#' && !inWord:
+			return r, nil
+		default:
+			word, inWord = append(word, c), true
+		}
+	}
+	switch {
+	case escaped:
+		return nil, UNTERMINATED_ESCAPE
+	case quote != 0:
+		return nil, UNTERMINATED_QUOTE
+	case inWord:
+		r = append(r, string(word))
+	}
+	return r, nil
+}
+
+// NewShell returns a shell running commands of the tree this FlagSet
+// belongs to, reading from in and writing prompts, help and errors to
+// out
+func (f *FlagSet) NewShell(in io.Reader, out io.Writer) *Shell {
+	root := f.Root()
+	return &Shell{Prompt: root.ProgramName() + "> ", root: root, in: in, out: out}
+}
+
+// AddShellKeyword adds the shell keyword below this FlagSet, opening a
+// shell on in and out
+func (f *FlagSet) AddShellKeyword(in io.Reader, out io.Writer) *FlagSet {
+	sh := f.NewShell(in, out)
+	s := f.NewFlagSet(SHELL_KEYWORD, "run commands interactively")
+	s.HookFunc = func(self *FlagSet) error {
+		return sh.Run(self.Context())
+	}
+	return s
+}
+
+func (sh *Shell) History() []string {
+	return sh.history
+}
+
+// Run reads and executes commands until end of input, exit or quit, or
+// until ctx is done; the errors of single commands are reported and do
+// not stop the loop
+func (sh *Shell) Run(ctx context.Context) error {
+	if sh.running {
+		return ALREADY_IN_SHELL
+	}
+	sh.running = true
+	restore := sh.root.saveShellState()
+	defer func() {
+		restore()
+		sh.running = false
+	}()
+	sh.root.Walk(func(s *FlagSet) {
+		s.Flags.Init(s.Flags.Name(), flag.ContinueOnError)
+		s.Flags.Usage = nil
+	})
+	sh.root.SetOutput(sh.out)
+	scanner := bufio.NewScanner(sh.in)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fmt.Fprint(sh.out, sh.Prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(sh.out)
+			return scanner.Err()
+		}
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasSuffix(line, "\t") {
+			fmt.Fprintln(sh.out, strings.Join(sh.Complete(strings.TrimSuffix(line, "\t")), " "))
+			continue
+		}
+		args, err := SplitCommandLine(line)
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		sh.history = append(sh.history, line)
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "history":
+			for i, h := range sh.history {
+				fmt.Fprintf(sh.out, "%5d  %s\n", i+1, h)
+			}
+			continue
+		}
+		sh.root.Reset()
+		if err := sh.root.ParseContext(ctx, args); err != nil && err != flag.ErrHelp && !alreadyReported(err) {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+		}
+	}
+}
+
+// shellState is what the shell changes in a FlagSet besides the values:
+// the outcome of the last parse, and how errors are reported
+type shellState struct {
+	origins  map[string]Origin
+	args     []string
+	ctx      context.Context
+	selected *FlagSet
+	handling flag.ErrorHandling
+	usage    func()
+	output   io.Writer
+}
+
+// saveShellState returns a function bringing back the state of every
+// FlagSet of the tree as it is now, so that the command running a shell
+// finds its own parse when the shell ends
+func (f *FlagSet) saveShellState() func() {
+	saved := map[*FlagSet]shellState{}
+	f.Walk(func(s *FlagSet) {
+		saved[s] = shellState{s.origins, s.args, s.ctx, s.selected, s.Flags.ErrorHandling(), s.Flags.Usage, s.output}
+	})
+	return func() {
+		for s, st := range saved {
+			s.origins, s.args, s.ctx, s.selected = st.origins, st.args, st.ctx, st.selected
+			s.Flags.Init(s.Flags.Name(), st.handling)
+			s.Flags.Usage, s.output = st.usage, st.output
+		}
+	}
+}
+
+// Complete returns the keywords, or the flags when the last word starts
+// with a dash, that can complete the last word of line
+func (sh *Shell) Complete(line string) []string {
+	words, err := SplitCommandLine(line)
+	if err != nil {
+		return nil
+	}
+	prefix := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		prefix, words = words[len(words)-1], words[:len(words)-1]
+	}
+	s := sh.root
+	for _, w := range words {
+		if strings.HasPrefix(w, "-") {
+			continue
+		}
+		if w == HELP_KEYWORD {
+			continue
+		}
+		sub, err := s.lookupKeyword(w)
+		if err != nil {
+			return nil
+		}
+		s = sub
+	}
+	candidates := []string{}
+	if strings.HasPrefix(prefix, "-") {
+		s.visitAll(func(fl *flag.Flag) {
+			if s.listed(fl) {
+				candidates = append(candidates, "-"+fl.Name)
+			}
+		})
+	} else {
+		candidates = s.visibleKeywords()
+		if len(s.subSets) > 0 {
+			candidates = append(candidates, HELP_KEYWORD)
+		}
+		if len(words) == 0 {
+			candidates = append(candidates, "exit", "history", "quit")
+		}
+	}
+	r := []string{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			r = append(r, c)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,92 @@
+package gu_flag
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	cases := []struct {
+		line  string
+		words []string
+		err   error
+	}{
+		{`hat  color -index=3`, []string{"hat", "color", "-index=3"}, nil},
+		{`say 'a  b' "c \"d\" \n" e\ f`, []string{"say", "a  b", `c "d" \n`, "e f"}, nil},
+		{`a '' "" # comment`, []string{"a", "", ""}, nil},
+		{`a b#c`, []string{"a", "b#c"}, nil},
+		{`say 'open`, nil, UNTERMINATED_QUOTE},
+		{`say end\`, nil, UNTERMINATED_ESCAPE},
+	}
+	for _, c := range cases {
+		words, err := SplitCommandLine(c.line)
+		if err != c.err || strings.Join(words, "|") != strings.Join(c.words, "|") {
+			t.Errorf("%s: got %q, %v", c.line, words, err)
+		}
+	}
+}
+
+func shellTree(in string, out *bytes.Buffer) (root *FlagSet, verbose *bool, got *[]string) {
+	root = NewRoot("prog", "")
+	verbose = root.Flags.Bool("verbose", false, "")
+	got = &[]string{}
+	say := root.NewFlagSet("say", "")
+	word := say.Flags.String("word", "", "")
+	say.HookFunc = func(self *FlagSet) error {
+		*got = append(*got, fmt.Sprintf("%s/%v", *word, *verbose))
+		return nil
+	}
+	root.AddShellKeyword(strings.NewReader(in), out)
+	return
+}
+
+func TestShell(t *testing.T) {
+	var out bytes.Buffer
+	root, _, got := shellTree("say -word=hi\n-verbose say -word='a b'\nsay -nosuch\nshell\nhistory\nexit\nsay -word=late\n", &out)
+	var postRunCtx context.Context
+	root.PostRun = func(ctx context.Context, self *FlagSet, err error) error {
+		if self.Parent() == nil && len(self.Args()) > 0 && self.Args()[0] == SHELL_KEYWORD {
+			postRunCtx = ctx
+		}
+		return err
+	}
+	ctx := context.WithValue(context.Background(), "key", "outer")
+	if err := root.ParseContext(ctx, []string{"-verbose", "shell"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(*got, ",") != "hi/false,a b/true" {
+		t.Errorf("commands got %q", *got)
+	}
+	if root.Selected() != root.subSets[SHELL_KEYWORD] || root.Origin("verbose") != OriginCommandLine {
+		t.Errorf("outer parse state lost: %v %v", root.Selected().Path(), root.Origin("verbose"))
+	}
+	if postRunCtx == nil || postRunCtx.Value("key") != "outer" {
+		t.Errorf("outer PostRun got context %v", postRunCtx)
+	}
+	for _, s := range []string{"flag provided but not defined: -nosuch", ALREADY_IN_SHELL.Error(), "    2  -verbose say -word='a b'"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("%q not in output:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestShellComplete(t *testing.T) {
+	var out bytes.Buffer
+	root, _, _ := shellTree("", &out)
+	sh := root.NewShell(strings.NewReader(""), &out)
+	cases := map[string]string{
+		"s":        "say,shell",
+		"":         "exit,help,history,quit,say,shell",
+		"say -w":   "-word",
+		"say -":    "-word",
+		"nosuch x": "",
+	}
+	for line, want := range cases {
+		if got := strings.Join(sh.Complete(line), ","); got != want {
+			t.Errorf("%q: got %q, want %q", line, got, want)
+		}
+	}
+}
@@ -4,11 +4,16 @@
 package gu_flag
 
 import (
-	"errors"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+
+	"github.com/maxcalandrelli/goutil/encoding/json"
+	"github.com/maxcalandrelli/goutil/log"
 )
 
 type (
@@ -17,11 +22,42 @@ type (
 		subCommand     string
 		usage          string
 		Flags          *flag.FlagSet
+		ownFlags       *flag.FlagSet
 		subSets        map[string]*FlagSet
 		parent         *FlagSet
 		NonKeywordArgs bool
+		GNUStyle       bool
+		PrefixMatching bool
+		ResponseFiles  bool
+		Hidden         bool
+		ResetOnParse   bool
 		HookFunc       func(self *FlagSet) error
+		PreRun         func(ctx context.Context, self *FlagSet) (context.Context, error)
+		PostRun        func(ctx context.Context, self *FlagSet, err error) error
 		args           []string
+		output         io.Writer
+		origins        map[string]Origin
+		envBound       bool
+		envPrefix      string
+		configs        []gu_json.JSONData
+		aliases        map[string]string
+		constraints    []constraint
+		keywordAliases []string
+		persistent     map[string]bool
+		positionals    []*PositionalArg
+		ctx            context.Context
+		hidden         map[string]bool
+		deprecated     map[string]*Deprecation
+		deprecation    *Deprecation
+		version        Version
+		logger         gu_log.Logger
+		selected       *FlagSet
+		executing      bool
+		pluginDirs     []string
+		pluginsEnabled bool
+		pluginsLoaded  bool
+		plugin         string
+		pluginOutput   io.Writer
 	}
 )
 
@@ -40,19 +76,42 @@ func (f *FlagSet) NArg() int {
 
 func (f *FlagSet) PrintDefaults() {
 	if f.parent != nil {
-		fmt.Fprintf(os.Stderr, "\n  %s: %s\n\n", f.subCommand, f.usage)
+		fmt.Fprintf(f.Output(), "\n  %s: %s\n\n", f.subCommand, f.usage)
+	}
+	f.writeFlags(f.Output(), func(fl *flag.Flag) bool { return !f.isInherited(fl) })
+	for _, k := range f.visibleKeywords() {
+		f.subSets[k].PrintDefaults()
 	}
-	f.Flags.PrintDefaults()
-	for _, sub := range f.subSets {
-		sub.PrintDefaults()
+}
+
+// Output returns the writer used for help and usage messages; unless
+// overridden with SetOutput, it is inherited from the parent keyword
+func (f *FlagSet) Output() io.Writer {
+	for s := f; s != nil; s = s.parent {
+		if s.output != nil {
+			return s.output
+		}
 	}
+	return os.Stderr
+}
+
+func (f *FlagSet) SetOutput(w io.Writer) {
+	f.output = w
 }
 
 func (f *FlagSet) Keywords() []string {
+	f.loadPlugins()
+	return f.definedKeywords()
+}
+
+// definedKeywords lists the keywords defined so far, without looking
+// for plugins
+func (f *FlagSet) definedKeywords() []string {
 	r := []string{}
 	for k, _ := range f.subSets {
 		r = append(r, k)
 	}
+	sort.Strings(r)
 	return r
 }
 
@@ -69,60 +128,310 @@ func (f *FlagSet) Usage() string {
 }
 
 func (f *FlagSet) Parse(args []string) error {
-	if ForceEqualAlways {
-		flags := []string{}
-		for len(args) > 0 && len(args[0]) > 0 && args[0][0] == '-' {
-			flags = append(flags, args[0])
-			args = args[1:]
-		}
-		if len(flags) > 0 {
-			if err := f.Flags.Parse(flags); err != nil {
-				return err
-			}
-		}
+	return f.ParseContext(context.Background(), args)
+}
+
+// ParseContext parses args and runs the hooks of the selected keyword
+// path, passing ctx to the first PreRun
+func (f *FlagSet) ParseContext(ctx context.Context, args []string) error {
+	if f.ResetOnParse {
+		f.Reset()
 	} else {
-		if err := f.Flags.Parse(args); err != nil {
+		f.Walk((*FlagSet).clearParseState)
+	}
+	var positions []argPosition
+	if f.responseFiles() {
+		var err error
+		if args, positions, err = expandResponseFiles(args); err != nil {
 			return err
 		}
-		args = f.Flags.Args()
+	}
+	leaf, err := f.parse(args)
+	if err != nil {
+		locateError(err, args, positions)
+		return err
+	}
+	f.Root().selected = leaf
+	if err := leaf.applySources(); err != nil {
+		return err
+	}
+	if err := leaf.checkConstraints(); err != nil {
+		return leaf.parseError(ConstraintViolation, "", err)
+	}
+	if err := f.settleRates(); err != nil {
+		return err
+	}
+	return leaf.run(ctx)
+}
+
+// parse consumes the flags of this subgrammar and descends along the
+// keywords, returning the FlagSet whose HookFunc has to be run
+func (f *FlagSet) parse(args []string) (*FlagSet, error) {
+	if len(f.plugin) > 0 {
+		f.args = args
+		return f, nil
+	}
+	if len(f.subSets) == 0 {
+		// only plugins can tell if the args start with a keyword
+		f.loadPlugins()
+	}
+	var err error
+	if f.gnuStyle() {
+		args, err = f.parseGNU(args)
+	} else {
+		args, err = f.parseStandard(args)
+	}
+	if err != nil {
+		f.flagError(err)
+		return nil, err
+	}
+	if err := f.markParsed(args); err != nil {
+		return nil, err
 	}
 	f.args = args
+	if len(f.subSets) == 0 && len(f.positionals) > 0 {
+		if err := f.assignPositionals(args); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
 	switch {
 	case len(args) == 0 && len(f.subSets) == 0:
-		return f.HookFunc(f)
+		return f, nil
 	case len(args) == 0 && len(f.subSets) != 0:
-		return errors.New(fmt.Sprintf("missing keyword (try one of: %s)", strings.Join(f.Keywords(), ",")))
+		pe := f.parseError(MissingKeyword, "", nil)
+		pe.Suggestions = f.visibleKeywords()
+		return nil, pe
 	case len(args) != 0 && len(f.subSets) == 0:
 		if f.NonKeywordArgs {
-			return f.HookFunc(f)
+			return f, nil
 		} else {
-			return errors.New(fmt.Sprintf("unexpected: %s", args[0]))
+			pe := f.parseError(UnexpectedPositional, args[0], nil)
+			pe.argsLeft = len(args)
+			return nil, pe
 		}
 	}
-	if s, ok := f.subSets[args[0]]; ok {
-		return s.Parse(args[1:])
-	} else {
-		return errors.New(fmt.Sprintf("unknown keyword: %s", args[0]))
+	if _, ok := f.subSets[args[0]]; !ok && args[0] == HELP_KEYWORD {
+		return nil, f.help(args[1:])
+	}
+	s, err := f.lookupKeyword(args[0])
+	if err == nil {
+		err = s.checkDeprecatedKeyword(args[0])
+	}
+	if err != nil {
+		markArgsLeft(err, len(args))
+		return nil, err
+	}
+	return s.parse(args[1:])
+}
+
+// parseStandard sets the flags at the beginning of args, in the forms
+// accepted by the standard flag package; with ForceEqualAlways, values
+// can only be given after an equal sign
+func (f *FlagSet) parseStandard(args []string) (rest []string, err error) {
+	left := 0
+	defer func() { markArgsLeft(err, left) }()
+	for len(args) > 0 {
+		left = len(args)
+		a := args[0]
+		if len(a) < 2 || a[0] != '-' {
+			break
+		}
+		args = args[1:]
+		if a == "--" {
+			break
+		}
+		name := strings.TrimPrefix(a[1:], "-")
+		if len(name) == 0 || name[0] == '-' || name[0] == '=' {
+			return nil, f.parseError(BadFlagValue, a, BAD_FLAG_SYNTAX)
+		}
+		value := ""
+		hasValue := false
+		op := byte(0)
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value, hasValue = name[:i], name[i+1:], true
+			name, op = f.setOperator(name)
+		}
+		fl, err := f.lookupFlag(name)
+		if err != nil {
+			return nil, err
+		}
+		if !hasValue {
+			switch {
+			case isBoolFlag(fl):
+				value = "true"
+			case ForceEqualAlways || len(args) == 0:
+				return nil, f.missingValue(a, name)
+			default:
+				value, args = args[0], args[1:]
+			}
+		}
+		if err := f.setFlag(a, name, value, op); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// markParsed lets Flags report Parsed and the args left, as if it had
+// parsed the command line itself; the -- in front keeps it from
+// interpreting args again
+func (f *FlagSet) markParsed(args []string) error {
+	return f.Flags.Parse(append([]string{"--"}, args...))
+}
+
+// flagError handles the errors in the flags as the Parse method of Flags
+// would: when Flags has a Usage function, as flag.CommandLine does, or
+// its ErrorHandling is not ContinueOnError, the error is printed
+// followed by the usage, the help of the keyword standing for a missing
+// Usage function, and the program exits or panics according to the
+// ErrorHandling. Otherwise, as for the keywords created by NewFlagSet,
+// whose Flags have no Usage function, nothing is printed and the error is just returned, to be reported by
+// the caller or by Execute. -h and -help always print the help of the
+// keyword. Under Execute errors are always just returned, leaving to it
+// the report and the exit code
+func (f *FlagSet) flagError(err error) {
+	handling := f.Flags.ErrorHandling()
+	if err == flag.ErrHelp {
+		f.PrintHelp()
+	} else if f.Root().executing {
+		return
+	} else if f.Flags.Usage != nil || handling != flag.ContinueOnError {
+		fmt.Fprintln(f.Output(), err)
+		if f.Flags.Usage != nil {
+			f.Flags.Usage()
+		} else {
+			f.PrintHelp()
+		}
+		if pe, ok := err.(*ParseError); ok {
+			pe.reported = true
+		}
+	}
+	switch {
+	case f.Root().executing:
+	case handling == flag.PanicOnError:
+		panic(err)
+	case handling == flag.ExitOnError && err == flag.ErrHelp:
+		os.Exit(0)
+	case handling == flag.ExitOnError:
+		os.Exit(2)
+	}
+}
+
+func (f *FlagSet) lookupFlag(name string) (*flag.Flag, error) {
+	if fl, _ := f.lookup(name); fl != nil {
+		return fl, nil
+	}
+	if name == "h" || name == "help" {
+		return nil, flag.ErrHelp
 	}
+	return nil, f.unknownFlag("-"+name, name)
+}
+
+func (f *FlagSet) unknownFlag(token, name string) error {
+	names := []string{}
+	f.visitAll(func(fl *flag.Flag) {
+		if !f.isHiddenFlag(fl.Name) {
+			names = append(names, fl.Name)
+		}
+	})
+	pe := f.parseError(UnknownFlag, token, nil)
+	pe.Flag = name
+	pe.Suggestions = suggestions(name, names)
+	return pe
 }
 
-func (f *FlagSet) NewFlagSet(name, usage string) *FlagSet {
+func (f *FlagSet) missingValue(token, name string) error {
+	pe := f.parseError(BadFlagValue, token, MISSING_FLAG_VALUE)
+	pe.Flag = name
+	return pe
+}
+
+// setFlag sets the named flag from the command line; op, if not 0, is
+// the operator of -name+= or -name-= applied to a set
+func (f *FlagSet) setFlag(token, name, value string, op byte) error {
+	if err := f.checkDeprecatedFlag(token, name); err != nil {
+		return err
+	}
+	fl, o := f.lookup(name)
+	sa, isSet := fl.Value.(*setArg)
+	if isSet {
+		sa.op = op
+	}
+	err := o.Flags.Set(name, value)
+	if isSet {
+		sa.op = 0
+	}
+	if err != nil {
+		pe := f.parseError(BadFlagValue, token, err)
+		pe.Flag, pe.Value = name, value
+		if isSet && err == NOT_IN_SET {
+			pe.Suggestions = suggestions(sa.rejected, sa.allowed)
+		}
+		if ee, ok := err.(*EnumError); ok && ee.Err == NOT_IN_SET {
+			pe.Suggestions = suggestions(ee.Value, ee.Allowed)
+		}
+		return pe
+	}
+	f.setOrigin(name, OriginCommandLine)
+	return f.forwardDeprecated(token, name, value, op)
+}
+
+// applySources sets the flags not given on the command line from the
+// environment or the configuration, along the selected keyword path
+func (f *FlagSet) applySources() error {
+	for _, s := range f.lineage() {
+		if err := s.applyEnvironment(); err != nil {
+			return err
+		}
+		if err := s.applyConfig(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newFlagSet(name, usage string, aliases []string) *FlagSet {
 	r := new(FlagSet)
 	r.subSets = make(map[string]*FlagSet)
 	r.subCommand = name
+	r.keywordAliases = aliases
 	r.usage = usage
 	r.Flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	r.Flags.Usage = nil
+	r.ownFlags = r.Flags
+	r.HookFunc = func(*FlagSet) error { return nil }
+	return r
+}
+
+func (f *FlagSet) NewFlagSet(name, usage string, aliases ...string) *FlagSet {
+	r := newFlagSet(name, usage, aliases)
 	r.parent = f
 	if r.parent == nil && len(name) != 0 {
 		r.parent = &MainSet
 	}
 	if r.parent != nil {
+		r.parent.checkKeywordClash(name, aliases)
 		r.parent.subSets[name] = r
 	}
-	r.HookFunc = func(*FlagSet) error { return nil }
 	return r
 }
 
-func NewFlagSet(name, usage string) *FlagSet {
-	return (*FlagSet)(nil).NewFlagSet(name, usage)
+func NewFlagSet(name, usage string, aliases ...string) *FlagSet {
+	return (*FlagSet)(nil).NewFlagSet(name, usage, aliases...)
+}
+
+// NewRoot returns the root of a grammar independent from MainSet and
+// flag.CommandLine; name is the program name shown in help
+func NewRoot(name, usage string) *FlagSet {
+	return newFlagSet(name, usage, nil)
+}
+
+// Walk visits this FlagSet and all the subgrammars below it, depth first
+// and in keyword order
+func (f *FlagSet) Walk(visit func(*FlagSet)) {
+	visit(f)
+	for _, k := range f.definedKeywords() {
+		f.subSets[k].Walk(visit)
+	}
 }
@@ -0,0 +1,230 @@
+// Typed repeatable flags parse each element of a list of values, as in
+// -port=80,443 or -label=env=prod,team=core, reporting the element that
+// could not be parsed
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DuplicateKeyPolicy int
+
+const (
+	DuplicateKeyError DuplicateKeyPolicy = iota
+	DuplicateKeyReplace
+	DuplicateKeyKeepFirst
+)
+
+var (
+	DUPLICATE_KEY error = errors.New("key already present")
+	NOT_KEY_VALUE error = errors.New("not in key=value form")
+
+	skipElement error = errors.New("element not stored")
+)
+
+type ElementError struct {
+	Element string
+	Err     error
+}
+
+func (ee *ElementError) Error() string {
+	return fmt.Sprintf("element %q: %v", ee.Element, ee.Err)
+}
+
+func (ee *ElementError) Unwrap() error {
+	return ee.Err
+}
+
+// typedListArg keeps the elements as strings, like the other repeatable
+// args, besides storing their parsed values in the typed destination
+type typedListArg struct {
+	listOrSetArg
+	elements   []string
+	resetTyped func()
+	get        func() interface{}
+	keyValue   bool
+}
+
+func (tla *typedListArg) Set(v string) error {
+	if tla.IsDefault() {
+		tla.resetTyped()
+	}
+	return tla.listOrSetArg.Set(v)
+}
+
+func (tla *typedListArg) reset() {
+	tla.resetTyped()
+	tla.listOrSetArg.reset()
+}
+
+func (tla *typedListArg) restoreDefault() {
+	tla.reset()
+	for _, v := range tla.defaults {
+		tla.inserter(v)
+	}
+	tla.setDefault(true)
+}
+
+func (tla *typedListArg) Get() interface{} {
+	return tla.get()
+}
+
+func numberError(err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return ne.Err
+	}
+	return err
+}
+
+func (f *FlagSet) typedListVar(name string, default_value []string, usage string, parse func(string) error, resetTyped func(), get func() interface{}) *typedListArg {
+	tla := &typedListArg{resetTyped: resetTyped, get: get}
+	resetTyped()
+	tla.listOrSetArg = *newListArg(
+		&tla.elements,
+		",",
+		default_value,
+		func(v string) string { return v },
+		func(v string) error {
+			if err := parse(v); err == skipElement {
+				return nil
+			} else if err != nil {
+				return &ElementError{Element: v, Err: err}
+			}
+			tla.elements = append(tla.elements, v)
+			return nil
+		},
+	)
+	f.Flags.Var(tla, name, usage)
+	return tla
+}
+
+func (f *FlagSet) IntList(name string, value []int, usage string) RepeatableArg {
+	return f.IntListVar(new([]int), name, value, usage)
+}
+
+func (f *FlagSet) IntListVar(p *[]int, name string, value []int, usage string) RepeatableArg {
+	default_value := []string{}
+	for _, v := range value {
+		default_value = append(default_value, strconv.Itoa(v))
+	}
+	tla := f.typedListVar(name, default_value, usage,
+		func(v string) error {
+			i, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return numberError(err)
+			}
+			*p = append(*p, i)
+			return nil
+		},
+		func() { *p = []int{} },
+		func() interface{} { return *p },
+	)
+	tla.factory = func(c *FlagSet, name string) RepeatableArg { return c.IntListVar(new([]int), name, value, usage) }
+	return tla
+}
+
+func (f *FlagSet) FloatList(name string, value []float64, usage string) RepeatableArg {
+	return f.FloatListVar(new([]float64), name, value, usage)
+}
+
+func (f *FlagSet) FloatListVar(p *[]float64, name string, value []float64, usage string) RepeatableArg {
+	default_value := []string{}
+	for _, v := range value {
+		default_value = append(default_value, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	tla := f.typedListVar(name, default_value, usage,
+		func(v string) error {
+			x, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return numberError(err)
+			}
+			*p = append(*p, x)
+			return nil
+		},
+		func() { *p = []float64{} },
+		func() interface{} { return *p },
+	)
+	tla.factory = func(c *FlagSet, name string) RepeatableArg { return c.FloatListVar(new([]float64), name, value, usage) }
+	return tla
+}
+
+func (f *FlagSet) DurationList(name string, value []time.Duration, usage string) RepeatableArg {
+	return f.DurationListVar(new([]time.Duration), name, value, usage)
+}
+
+func (f *FlagSet) DurationListVar(p *[]time.Duration, name string, value []time.Duration, usage string) RepeatableArg {
+	default_value := []string{}
+	for _, v := range value {
+		default_value = append(default_value, v.String())
+	}
+	tla := f.typedListVar(name, default_value, usage,
+		func(v string) error {
+			d, err := time.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				return err
+			}
+			*p = append(*p, d)
+			return nil
+		},
+		func() { *p = []time.Duration{} },
+		func() interface{} { return *p },
+	)
+	tla.factory = func(c *FlagSet, name string) RepeatableArg {
+		return c.DurationListVar(new([]time.Duration), name, value, usage)
+	}
+	return tla
+}
+
+func (f *FlagSet) Map(name string, value map[string]string, usage string, policy DuplicateKeyPolicy) RepeatableArg {
+	return f.MapVar(new(map[string]string), name, value, usage, policy)
+}
+
+// MapVar defines a flag taking key=value elements; policy decides what
+// happens when a key is given more than once
+func (f *FlagSet) MapVar(p *map[string]string, name string, value map[string]string, usage string, policy DuplicateKeyPolicy) RepeatableArg {
+	default_value := []string{}
+	for k, v := range value {
+		default_value = append(default_value, k+"="+v)
+	}
+	sort.Strings(default_value)
+	var tla *typedListArg
+	tla = f.typedListVar(name, default_value, usage,
+		func(v string) error {
+			i := strings.Index(v, "=")
+			if i < 0 {
+				return NOT_KEY_VALUE
+			}
+			key, value := v[:i], v[i+1:]
+			if _, present := (*p)[key]; present {
+				switch policy {
+				case DuplicateKeyKeepFirst:
+					return skipElement
+				case DuplicateKeyReplace:
+					for n, e := range tla.elements {
+						if strings.HasPrefix(e, key+"=") {
+							tla.elements[n] = v
+						}
+					}
+					(*p)[key] = value
+					return skipElement
+				default:
+					return DUPLICATE_KEY
+				}
+			}
+			(*p)[key] = value
+			return nil
+		},
+		func() { *p = map[string]string{} },
+		func() interface{} { return *p },
+	)
+	tla.keyValue = true
+	tla.factory = func(c *FlagSet, name string) RepeatableArg {
+		return c.MapVar(new(map[string]string), name, value, usage, policy)
+	}
+	return tla
+}
@@ -0,0 +1,70 @@
+package gu_flag
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTypedLists(t *testing.T) {
+	root := NewRoot("prog", "")
+	var p []int
+	var w []float64
+	var d []time.Duration
+	root.IntListVar(&p, "port", []int{80}, "")
+	root.FloatListVar(&w, "weight", nil, "")
+	root.DurationListVar(&d, "retry", []time.Duration{time.Second}, "")
+	if fmt.Sprint(p, d) != "[80] [1s]" {
+		t.Errorf("defaults %v %v", p, d)
+	}
+	if err := root.Parse([]string{"-port=8080, 8443", "-port=9000", "-weight=0.5,2", "-retry=1m"}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(p, w, d) != "[8080 8443 9000] [0.5 2] [1m0s]" {
+		t.Errorf("got %v %v %v", p, w, d)
+	}
+	root.Reset()
+	if fmt.Sprint(p, w, d) != "[80] [] [1s]" {
+		t.Errorf("defaults not restored: %v %v %v", p, w, d)
+	}
+}
+
+func TestTypedListErrors(t *testing.T) {
+	cases := map[string]error{
+		"-port=80,http":              strconv.ErrSyntax,
+		"-port=99999999999999999999": strconv.ErrRange,
+		"-label=a=1,b":               NOT_KEY_VALUE,
+		"-label=a=1,a=2":             DUPLICATE_KEY,
+	}
+	for arg, want := range cases {
+		root := NewRoot("prog", "")
+		root.IntList("port", nil, "")
+		root.Map("label", nil, "", DuplicateKeyError)
+		err := root.Parse([]string{arg})
+		var pe *ParseError
+		var ee *ElementError
+		if !errors.As(err, &pe) || pe.Kind != BadFlagValue || !errors.As(err, &ee) || !errors.Is(err, want) {
+			t.Errorf("%s: got %v", arg, err)
+		}
+	}
+}
+
+func TestMapPolicies(t *testing.T) {
+	cases := map[DuplicateKeyPolicy]string{
+		DuplicateKeyReplace:   "map[a:2 b:3] [a=2 b=3]",
+		DuplicateKeyKeepFirst: "map[a:1 b:3] [a=1 b=3]",
+	}
+	for policy, want := range cases {
+		root := NewRoot("prog", "")
+		var m map[string]string
+		root.MapVar(&m, "label", map[string]string{"z": "0"}, "", policy)
+		if err := root.Parse([]string{"-label=a=1,b=3", "-label=a=2"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(m, root.Flags.Lookup("label").Value); got != want {
+			t.Errorf("policy %v: got %s, want %s", policy, got, want)
+		}
+	}
+}
@@ -29,6 +29,14 @@ type ThrottledQuantity interface {
 	GetAverageRate() float64
 }
 
+// RateSetter is implemented by the throttled quantities whose maximum
+// rate and mode can be changed after their definition; like the other
+// setup methods of a Throttler, SetMaxRate panics if the throttler is
+// frozen or has an operation in progress
+type RateSetter interface {
+	SetMaxRate(rate float64, mode ThrottleType)
+}
+
 type ThrottlingPause interface {
 	Qty() ThrottledQuantity
 	Amount() time.Duration
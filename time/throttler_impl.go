@@ -43,6 +43,15 @@ func (tq throttledQuantity) GetThrottleType() ThrottleType {
 	return tq.mode
 }
 
+func (tq *throttledQuantity) SetMaxRate(rate float64, mode ThrottleType) {
+	t := tq.throttler.(*throttler)
+	t.checkCallable("SetMaxRate", true, false)
+	t.lock.Lock()
+	tq.maxRate = rate
+	tq.mode = mode
+	t.lock.Unlock()
+}
+
 func (tq throttledQuantity) GetUnits() float64 {
 	return tq.valueUnits
 }